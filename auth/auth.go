@@ -0,0 +1,211 @@
+// Package auth identifies the caller of every RPC.
+//
+// Callers send "authorization: Bearer <credential>" metadata, where the credential is
+// either a static API key or a JWT signed with HS256 (a shared secret) or RS256 (a key
+// from a local JWKS file). The interceptors reject calls without a valid credential with
+// UNAUTHENTICATED and put the caller's Identity in the context for the handlers.
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataKey is the metadata header carrying the credential
+const MetadataKey = "authorization"
+
+// clockSkew is how far off the issuer's clock may be when checking exp and nbf
+const clockSkew = 30 * time.Second
+
+// How a caller proved who they are
+const (
+	MethodAPIKey = "api-key"
+	MethodJWT    = "jwt"
+)
+
+// Identity is the authenticated caller
+type Identity struct {
+	Subject string   // API key name, or the JWT's sub claim
+	Tenant  string   // from the API key's entry or the JWT's tenant claim, empty for the default tenant
+	Roles   []string // from the API key's entry or the JWT's roles claim, see the policy package
+	Method  string
+}
+
+// claims are the JWT claims we read, tenant and roles are our own
+type claims struct {
+	jwt.RegisteredClaims
+	Tenant string   `json:"tenant,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the caller's identity, ok is false when authentication is off
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// Options say where the credentials are loaded from, at least one file must be set
+type Options struct {
+	APIKeysFile     string // YAML list of API key names and the SHA-256 of each key
+	HS256SecretFile string // shared secret for HS256 JWTs
+	JWKSFile        string // public keys for RS256 JWTs
+	Issuer          string // required iss claim, any when empty
+	Audience        string // required aud claim, any when empty
+}
+
+// Authenticator checks credentials against keys loaded at startup
+type Authenticator struct {
+	apiKeys    map[[sha256.Size]byte]*Identity
+	hmacSecret []byte
+	rsaKeys    map[string]*rsa.PublicKey // by kid
+	parser     *jwt.Parser
+}
+
+func New(opts Options) (*Authenticator, error) {
+	if opts.APIKeysFile == "" && opts.HS256SecretFile == "" && opts.JWKSFile == "" {
+		return nil, errors.New("no API keys, JWT secret or JWKS file to authenticate against")
+	}
+
+	a := &Authenticator{}
+	var err error
+	if opts.APIKeysFile != "" {
+		if a.apiKeys, err = loadAPIKeys(opts.APIKeysFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var algorithms []string
+	if opts.HS256SecretFile != "" {
+		if a.hmacSecret, err = loadSecret(opts.HS256SecretFile); err != nil {
+			return nil, err
+		}
+		algorithms = append(algorithms, jwt.SigningMethodHS256.Alg())
+	}
+	if opts.JWKSFile != "" {
+		if a.rsaKeys, err = loadJWKS(opts.JWKSFile); err != nil {
+			return nil, err
+		}
+		algorithms = append(algorithms, jwt.SigningMethodRS256.Alg())
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	a.parser = jwt.NewParser(parserOpts...)
+	return a, nil
+}
+
+// Unary is a grpc.UnaryServerInterceptor
+func (a *Authenticator) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id, err := a.Authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(NewContext(ctx, id), req)
+}
+
+// Stream is a grpc.StreamServerInterceptor
+func (a *Authenticator) Stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id, err := a.Authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &identifiedStream{ServerStream: ss, ctx: NewContext(ss.Context(), id)})
+}
+
+type identifiedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identifiedStream) Context() context.Context {
+	return s.ctx
+}
+
+// Authenticate returns the identity behind the credential in ctx's metadata,
+// or an UNAUTHENTICATED error. The credential itself never ends up in an error or a log.
+func (a *Authenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	credential, err := bearer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// JWTs are three dot separated parts, API keys never contain a dot
+	if strings.Count(credential, ".") != 2 {
+		if id, ok := a.apiKeys[sha256.Sum256([]byte(credential))]; ok {
+			return id, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	if a.hmacSecret == nil && a.rsaKeys == nil {
+		return nil, status.Error(codes.Unauthenticated, "JWTs aren't accepted, use an API key")
+	}
+	var claims claims
+	if _, err := a.parser.ParseWithClaims(credential, &claims, a.key); err != nil {
+		slog.DebugContext(ctx, "rejected JWT", "error", err)
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	if claims.Subject == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid token: no sub claim")
+	}
+	return &Identity{Subject: claims.Subject, Tenant: claims.Tenant, Roles: claims.Roles, Method: MethodJWT}, nil
+}
+
+// key picks the key a JWT must be signed with, the parser has already checked its alg is allowed
+func (a *Authenticator) key(token *jwt.Token) (any, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		return a.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := a.rsaKeys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(a.rsaKeys) == 1 {
+		for _, key := range a.rsaKeys {
+			return key, nil
+		}
+	}
+	return nil, errors.New("signed with an unknown key")
+}
+
+// bearer returns the credential from "authorization: Bearer <credential>"
+func bearer(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(MetadataKey)
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+	scheme, credential, found := strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(credential) == "" {
+		return "", status.Error(codes.Unauthenticated, `authorization metadata must be "Bearer <API key or JWT>"`)
+	}
+	return strings.TrimSpace(credential), nil
+}
@@ -0,0 +1,279 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jerryhong21/todo-grpc/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiKey   = "ci-bot-secret-key"
+	secret   = "0123456789abcdef0123456789abcdef"
+	issuer   = "https://issuer.example"
+	audience = "todo-grpc"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+	set := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	raw, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, "jwks.json", string(raw))
+}
+
+func withCredential(credential string) context.Context {
+	if credential == "" {
+		return context.Background()
+	}
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.MetadataKey, credential))
+}
+
+func TestAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := auth.New(auth.Options{
+		APIKeysFile: writeFile(t, "keys.yaml", `keys:
+  - subject: ci-bot
+    tenant: platform
+    roles: [editor]
+    sha256: `+keyHash(apiKey)+"\n"),
+		HS256SecretFile: writeFile(t, "secret", secret+"\n"),
+		JWKSFile:        writeJWKS(t, "key-1", &rsaKey.PublicKey),
+		Issuer:          issuer,
+		Audience:        audience,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":    "alice",
+			"iss":    issuer,
+			"aud":    audience,
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"tenant": "team-a",
+			"roles":  []string{"viewer"},
+		}
+	}
+	hs256 := func(claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	rs256 := func(kid string, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	without := func(claim string) jwt.MapClaims {
+		claims := valid()
+		delete(claims, claim)
+		return claims
+	}
+	with := func(claim string, value any) jwt.MapClaims {
+		claims := valid()
+		claims[claim] = value
+		return claims
+	}
+
+	tests := []struct {
+		name       string
+		credential string
+		want       *auth.Identity // nil when the call must be rejected
+	}{
+		{name: "no credential"},
+		{name: "not a bearer", credential: "Basic " + apiKey},
+		{name: "empty bearer", credential: "Bearer  "},
+		{
+			name:       "API key",
+			credential: "Bearer " + apiKey,
+			want:       &auth.Identity{Subject: "ci-bot", Tenant: "platform", Roles: []string{"editor"}, Method: auth.MethodAPIKey},
+		},
+		{
+			name:       "scheme is case insensitive",
+			credential: "bearer " + apiKey,
+			want:       &auth.Identity{Subject: "ci-bot", Tenant: "platform", Roles: []string{"editor"}, Method: auth.MethodAPIKey},
+		},
+		{name: "unknown API key", credential: "Bearer not-a-key"},
+		{
+			name:       "HS256 JWT",
+			credential: "Bearer " + hs256(valid()),
+			want:       &auth.Identity{Subject: "alice", Tenant: "team-a", Roles: []string{"viewer"}, Method: auth.MethodJWT},
+		},
+		{
+			name:       "RS256 JWT",
+			credential: "Bearer " + rs256("key-1", rsaKey, valid()),
+			want:       &auth.Identity{Subject: "alice", Tenant: "team-a", Roles: []string{"viewer"}, Method: auth.MethodJWT},
+		},
+		{name: "RS256 JWT with an unknown kid", credential: "Bearer " + rs256("key-2", rsaKey, valid())},
+		{name: "RS256 JWT signed with another key", credential: "Bearer " + rs256("key-1", otherKey, valid())},
+		{name: "HS256 JWT signed with another secret", credential: "Bearer " + func() string {
+			signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("another secret that is long enough"))
+			return signed
+		}()},
+		{name: "unsigned JWT", credential: "Bearer " + func() string {
+			signed, _ := jwt.NewWithClaims(jwt.SigningMethodNone, valid()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return signed
+		}()},
+		{name: "expired", credential: "Bearer " + hs256(with("exp", time.Now().Add(-time.Hour).Unix()))},
+		{name: "no exp", credential: "Bearer " + hs256(without("exp"))},
+		{name: "no sub", credential: "Bearer " + hs256(without("sub"))},
+		{name: "wrong issuer", credential: "Bearer " + hs256(with("iss", "https://elsewhere.example"))},
+		{name: "wrong audience", credential: "Bearer " + hs256(with("aud", "someone-else"))},
+		{
+			name:       "exp within the clock skew",
+			credential: "Bearer " + hs256(with("exp", time.Now().Add(-10*time.Second).Unix())),
+			want:       &auth.Identity{Subject: "alice", Tenant: "team-a", Roles: []string{"viewer"}, Method: auth.MethodJWT},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := a.Authenticate(withCredential(tt.credential))
+			if tt.want == nil {
+				if status.Code(err) != codes.Unauthenticated {
+					t.Fatalf("got %+v, %v, want UNAUTHENTICATED", id, err)
+				}
+				if leaked := strings.TrimSpace(strings.TrimPrefix(tt.credential, "Bearer ")); leaked != "" && strings.Contains(err.Error(), leaked) {
+					t.Fatalf("error %q gives the credential away", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if id.Subject != tt.want.Subject || id.Tenant != tt.want.Tenant || id.Method != tt.want.Method || !slices.Equal(id.Roles, tt.want.Roles) {
+				t.Fatalf("got %+v, want %+v", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeysOnly(t *testing.T) {
+	a, err := auth.New(auth.Options{APIKeysFile: writeFile(t, "keys.yaml", "keys:\n  - subject: ci-bot\n    sha256: "+keyHash(apiKey)+"\n")})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice"}).SignedString([]byte(secret))
+	if _, err := a.Authenticate(withCredential("Bearer " + token)); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("a JWT was accepted without a JWT secret or JWKS: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(t *testing.T) auth.Options
+		wantErr string
+	}{
+		{
+			name:    "nothing to authenticate against",
+			opts:    func(t *testing.T) auth.Options { return auth.Options{} },
+			wantErr: "no API keys",
+		},
+		{
+			name: "short secret",
+			opts: func(t *testing.T) auth.Options {
+				return auth.Options{HS256SecretFile: writeFile(t, "secret", "too short")}
+			},
+			wantErr: "at least 32 bytes",
+		},
+		{
+			name: "key without a subject",
+			opts: func(t *testing.T) auth.Options {
+				return auth.Options{APIKeysFile: writeFile(t, "keys.yaml", "keys:\n  - sha256: "+keyHash(apiKey)+"\n")}
+			},
+			wantErr: "has no subject",
+		},
+		{
+			name: "key that isn't a SHA-256",
+			opts: func(t *testing.T) auth.Options {
+				return auth.Options{APIKeysFile: writeFile(t, "keys.yaml", "keys:\n  - subject: ci-bot\n    sha256: "+apiKey+"\n")}
+			},
+			wantErr: "hex SHA-256",
+		},
+		{
+			name: "key listed twice",
+			opts: func(t *testing.T) auth.Options {
+				entry := "  - subject: ci-bot\n    sha256: " + keyHash(apiKey) + "\n"
+				return auth.Options{APIKeysFile: writeFile(t, "keys.yaml", "keys:\n"+entry+entry)}
+			},
+			wantErr: "listed twice",
+		},
+		{
+			name: "unknown field",
+			opts: func(t *testing.T) auth.Options {
+				return auth.Options{APIKeysFile: writeFile(t, "keys.yaml", "keys:\n  - subject: ci-bot\n    key: "+apiKey+"\n")}
+			},
+			wantErr: "failed to read API keys file",
+		},
+		{
+			name: "JWKS without RS256 keys",
+			opts: func(t *testing.T) auth.Options {
+				return auth.Options{JWKSFile: writeFile(t, "jwks.json", `{"keys":[{"kty":"EC","kid":"k"}]}`)}
+			},
+			wantErr: "no RS256 signing keys",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.New(tt.opts(t))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// minSecretLength is the shortest HS256 secret we accept, 256 bits as RFC 7518 asks for
+const minSecretLength = 32
+
+// apiKeysFile lists the accepted API keys. Only their SHA-256 is stored, so the file
+// itself doesn't give anyone access:
+//
+//	keys:
+//	  - subject: ci-bot
+//	    tenant: platform
+//	    roles: [editor]
+//	    sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+type apiKeysFile struct {
+	Keys []struct {
+		Subject string   `yaml:"subject"`
+		Tenant  string   `yaml:"tenant"`
+		Roles   []string `yaml:"roles"`
+		SHA256  string   `yaml:"sha256"`
+	} `yaml:"keys"`
+}
+
+func loadAPIKeys(path string) (map[[sha256.Size]byte]*Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys file: %w", err)
+	}
+	defer f.Close()
+
+	var file apiKeysFile
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to read API keys file %s: %w", path, err)
+	}
+
+	keys := make(map[[sha256.Size]byte]*Identity, len(file.Keys))
+	for i, key := range file.Keys {
+		if key.Subject == "" {
+			return nil, fmt.Errorf("API key %d in %s has no subject", i, path)
+		}
+		sum, err := hex.DecodeString(key.SHA256)
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("API key %q in %s needs a hex SHA-256 of the key", key.Subject, path)
+		}
+		hash := [sha256.Size]byte(sum)
+		if _, dup := keys[hash]; dup {
+			return nil, fmt.Errorf("API key %q in %s is listed twice", key.Subject, path)
+		}
+		keys[hash] = &Identity{Subject: key.Subject, Tenant: key.Tenant, Roles: key.Roles, Method: MethodAPIKey}
+	}
+	return keys, nil
+}
+
+func loadSecret(path string) ([]byte, error) {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT secret: %w", err)
+	}
+	// editors like to end files with a newline, it isn't part of the secret
+	secret = bytes.TrimSpace(secret)
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("JWT secret in %s must be at least %d bytes", path, minSecretLength)
+	}
+	return secret, nil
+}
+
+// jwks is a JSON Web Key Set (RFC 7517), only the RSA signing keys in it are used
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	var set jwks
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("failed to read JWKS %s: %w", path, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		key, err := rsaPublicKey(k.N, k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %q in JWKS %s: %w", k.Kid, path, err)
+		}
+		if _, dup := keys[k.Kid]; dup {
+			return nil, fmt.Errorf("key id %q is used twice in JWKS %s", k.Kid, path)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RS256 signing keys in JWKS %s", path)
+	}
+	return keys, nil
+}
+
+func rsaPublicKey(n, e string) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, fmt.Errorf("bad modulus: %w", err)
+	}
+	exponent, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, fmt.Errorf("bad exponent: %w", err)
+	}
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}
+	if key.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+	if key.E < 3 || key.E%2 == 0 {
+		return nil, errors.New("bad exponent")
+	}
+	return key, nil
+}
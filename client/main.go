@@ -4,20 +4,81 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
-	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"github.com/jerryhong21/todo-grpc/config"
 	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/tlsconfig"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	// "google.golang.org/protobuf/types/known/emptypb"
 )
 
+// cfg is loaded once at startup, see the config package
+var cfg *config.Client
+
 func main() {
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	var err error
+	cfg, err = config.LoadClient(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.PrintConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			log.Fatalf("Failed to print config: %v", err)
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config:\n%v", err)
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.TLS.Enabled {
+		tlsConfig, err := tlsconfig.Client(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ServerName)
+		if err != nil {
+			log.Fatalf("Failed to set up TLS: %v", err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	secrets := callMetadata{}
+	if cfg.Token != "" {
+		secrets["authorization"] = "Bearer " + cfg.Token.Reveal()
+	}
+	if cfg.SCToken != "" {
+		secrets["safetyculture-token"] = cfg.SCToken.Reveal()
+	}
+	if len(secrets) > 0 && !cfg.TLS.Enabled {
+		log.Printf("Warning: sending tokens without TLS, anyone on the network path can read them")
+	}
+	if cfg.Tenant != "" {
+		secrets["x-tenant-id"] = cfg.Tenant
+	}
+	if len(secrets) > 0 {
+		opts = append(opts, grpc.WithPerRPCCredentials(secrets))
+	}
+
+	conn, err := grpc.NewClient(cfg.ServerAddr, opts...)
 	if err != nil {
 		log.Fatalf("Couldn't connect to server: %v", err)
 	}
@@ -34,7 +95,10 @@ func main() {
 		fmt.Println("3. Update Todo")
 		fmt.Println("4. Delete Todo")
 		fmt.Println("5. List Todos")
-		fmt.Println("6. Exit")
+		fmt.Println("6. Sync with SafetyCulture")
+		fmt.Println("7. Assign Todo")
+		fmt.Println("8. Share Todo")
+		fmt.Println("9. Exit")
 		fmt.Print("Choose an option: ")
 
 		option, _ := reader.ReadString('\n')
@@ -45,13 +109,19 @@ func main() {
 			createTodo(client, reader)
 		case "2":
 			getTodo(client, reader)
-		// case "3":
-		//     updateTodo(client, reader)
+		case "3":
+			updateTodo(client, reader)
 		case "4":
 			bulkDeleteTodo(client, reader)
-		// case "5":
-		//     listTodos(client)
+		case "5":
+			listTodos(client, reader)
 		case "6":
+			syncNow(client)
+		case "7":
+			assignTodo(client, reader)
+		case "8":
+			shareTodo(client, reader)
+		case "9":
 			fmt.Println("Exiting...")
 			return
 		default:
@@ -61,10 +131,90 @@ func main() {
 
 }
 
+// callMetadata sends the tokens and tenant from the config as metadata on every call
+type callMetadata map[string]string
+
+func (m callMetadata) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return m, nil
+}
+
+// RequireTransportSecurity is false so tokens also work against a local plaintext server, main warns about it
+func (m callMetadata) RequireTransportSecurity() bool {
+	return false
+}
+
+// printError explains a failed call based on its gRPC status code,
+// so the user gets a hint on what to do rather than a raw error
+func printError(action string, err error) {
+	st := status.Convert(err)
+
+	switch st.Code() {
+	case codes.NotFound:
+		fmt.Printf("Error %s: not found (%s)\n", action, st.Message())
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		fmt.Printf("Error %s: invalid request (%s)\n", action, st.Message())
+		for _, detail := range st.Details() {
+			if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+				for _, violation := range badRequest.GetFieldViolations() {
+					fmt.Printf("  %s: %s\n", violation.GetField(), violation.GetDescription())
+				}
+			}
+		}
+	case codes.Aborted:
+		fmt.Printf("Error %s: the todo was changed by someone else, get it again and retry (%s)\n", action, st.Message())
+	case codes.Unauthenticated:
+		fmt.Printf("Error %s: not signed in, set TODO_TOKEN to your API key or JWT (%s)\n", action, st.Message())
+	case codes.PermissionDenied:
+		for _, detail := range st.Details() {
+			// the server's policy.ReasonMissingPermission, the client doesn't need the policy engine to match it
+			if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetReason() == "MISSING_PERMISSION" {
+				fmt.Printf("Error %s: your roles don't grant the %s permission\n", action, info.GetMetadata()["permission"])
+				return
+			}
+		}
+		fmt.Printf("Error %s: not allowed (%s)\n", action, st.Message())
+	case codes.ResourceExhausted:
+		wait := "a moment"
+		for _, detail := range st.Details() {
+			switch detail := detail.(type) {
+			case *errdetails.RetryInfo:
+				wait = detail.GetRetryDelay().AsDuration().String()
+			case *errdetails.QuotaFailure:
+				// retrying won't help until some todos are deleted
+				fmt.Printf("Error %s: over quota (%s)\n", action, st.Message())
+				return
+			}
+		}
+		fmt.Printf("Error %s: rate limited, try again in %s\n", action, wait)
+	case codes.Unavailable, codes.DeadlineExceeded:
+		fmt.Printf("Error %s: SafetyCulture is unreachable, try again later (%s)\n", action, st.Message())
+	default:
+		fmt.Printf("Error %s: %v\n", action, err)
+	}
+}
+
+// withRetries gives call up to cfg.Attempts attempts of cfg.Timeout each, retrying when the server
+// was too slow or unreachable. Only use it for calls that are safe to repeat, e.g. ones sent with an idempotency key.
+func withRetries(call func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; attempt < cfg.Attempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout.Duration)
+		err = call(ctx)
+		cancel()
+
+		switch status.Code(err) {
+		case codes.DeadlineExceeded, codes.Unavailable:
+			continue
+		}
+		return err
+	}
+	return err
+}
+
 func validateUUID(id string) bool {
-	// validate ID in UUID format
+	// validate ID in UUID format, the server only accepts the hyphenated form
 	_, err := uuid.Parse(id)
-	return err == nil
+	return err == nil && len(id) == 36
 }
 
 // Sends the server a request
@@ -73,10 +223,10 @@ func validateUUID(id string) bool {
 // so no need, reader, is a different story
 func createTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
 
-	fmt.Print("Enter TODO ID: ")
+	fmt.Print("Enter TODO ID (blank to let the server pick one): ")
 	id, _ := reader.ReadString('\n')
 	id = strings.TrimSpace(id)
-	if !validateUUID(id) {
+	if id != "" && !validateUUID(id) {
 		fmt.Printf("uuid %v is not valid!\n", id)
 		return
 	}
@@ -89,23 +239,24 @@ func createTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
 	description, _ := reader.ReadString('\n')
 	description = strings.TrimSpace(description)
 
-	// to prevent client hangs, we time it out for one second
-	// craetes a context with a timeout
-	// this means any operation using this context must complete within 1 second
-	// so we pass this into our gRPC calls to the server
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	// "defer" schedules the cancel() call to run when the current function completes
-	defer cancel()
-
-	// passing ctx into this function ensures this function lasts 1 second at most
-	res, err := client.CreateTodo(ctx, &pb.CreateTodoRequest{
+	req := &pb.CreateTodoRequest{
 		Id:          id,
 		Title:       title,
 		Description: description,
+		// the same key is sent on every attempt, so a retry can't create the todo twice
+		IdempotencyKey: uuid.NewString(),
+	}
+
+	// to prevent client hangs, each attempt is timed out after one second
+	var res *pb.Todo
+	err := withRetries(func(ctx context.Context) error {
+		var err error
+		res, err = client.CreateTodo(ctx, req)
+		return err
 	})
 
 	if err != nil {
-		fmt.Printf("Error creating todo: %v", err)
+		printError("creating todo", err)
 		return
 	}
 
@@ -127,7 +278,7 @@ func getTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
 	}
 
 	// create context and call the client
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout.Duration)
 	defer cancel()
 
 	retrieved, err := client.GetTodo(ctx, &pb.GetTodoRequest{
@@ -135,7 +286,7 @@ func getTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
 	})
 
 	if err != nil {
-		fmt.Printf("Error getting todo: %v", err)
+		printError("getting todo", err)
 		return
 	}
 
@@ -143,39 +294,269 @@ func getTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
 	fmt.Printf("id: %v\n", retrieved.GetId())
 	fmt.Printf("Title: %v\n", retrieved.GetTitle())
 	fmt.Printf("Description: %v\n", retrieved.GetDescription())
+	fmt.Printf("Completed: %v\n", retrieved.GetCompleted())
+	fmt.Printf("Sync state: %v\n", retrieved.GetSyncState())
+	fmt.Printf("Version: %v\n", retrieved.GetVersion())
+	if retrieved.GetOwner() != "" {
+		fmt.Printf("Owner: %v\n", retrieved.GetOwner())
+		fmt.Printf("Assignees: %v\n", strings.Join(retrieved.GetAssignees(), ", "))
+		fmt.Printf("Shared with: %v\n", strings.Join(retrieved.GetSharedWith(), ", "))
+	}
 }
 
-// TODO: Implement bulk deletion functionality
-// Make the CLI ask for multiple ids, isntead of just one
-func bulkDeleteTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
+// Only the fields the user fills in are sent in the update mask,
+// so leaving a prompt blank keeps the current value on the server
+func updateTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
 
-	fmt.Print("Enter Todo ID to delete: ")
+	fmt.Print("Enter TODO ID: ")
 	id, _ := reader.ReadString('\n')
 	id = strings.TrimSpace(id)
-
-	// checking for valid ID
 	if !validateUUID(id) {
 		fmt.Printf("uuid %v is not valid!\n", id)
 		return
 	}
 
-	// add the singular id to the string of ids
-	ids := []string{}
-	ids = append(ids, id)
+	req := &pb.UpdateTodoRequest{
+		Id:         id,
+		UpdateMask: &fieldmaskpb.FieldMask{},
+	}
+
+	fmt.Print("Enter new title (blank to keep): ")
+	title, _ := reader.ReadString('\n')
+	if title = strings.TrimSpace(title); title != "" {
+		req.Title = title
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "title")
+	}
+
+	fmt.Print("Enter new description (blank to keep): ")
+	description, _ := reader.ReadString('\n')
+	if description = strings.TrimSpace(description); description != "" {
+		req.Description = description
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "description")
+	}
+
+	fmt.Print("Completed? (y/n, blank to keep): ")
+	completed, _ := reader.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(completed)) {
+	case "y":
+		req.Completed = true
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "completed")
+	case "n":
+		req.Completed = false
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "completed")
+	}
+
+	if len(req.UpdateMask.Paths) == 0 {
+		fmt.Println("Nothing to update")
+		return
+	}
+
+	fmt.Print("Expected version (blank to update whatever the current version is): ")
+	version, _ := reader.ReadString('\n')
+	if version = strings.TrimSpace(version); version != "" {
+		v, err := strconv.ParseInt(version, 10, 64)
+		if err != nil || v <= 0 {
+			fmt.Printf("version %v is not valid!\n", version)
+			return
+		}
+		req.Version = v
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout.Duration)
+	defer cancel()
+
+	res, err := client.UpdateTodo(ctx, req)
+	if err != nil {
+		printError("updating todo", err)
+		return
+	}
+
+	jsonData, _ := json.MarshalIndent(res, "", "  ")
+	fmt.Printf("Updated Todo:\n %s", jsonData)
+}
+
+// Replaces who the todo is assigned to with the subjects entered on one line
+func assignTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
+	id, subjects, ok := readSubjects(reader, "Enter assignees (separated by spaces or commas, blank to unassign everyone): ")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout.Duration)
+	defer cancel()
+
+	res, err := client.AssignTodo(ctx, &pb.AssignTodoRequest{Id: id, Assignees: subjects})
+	if err != nil {
+		printError("assigning todo", err)
+		return
+	}
 
-	// create context and wait
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	// "defer" schedules the cancel() call to run when the current function completes
+	jsonData, _ := json.MarshalIndent(res, "", "  ")
+	fmt.Printf("Assigned Todo:\n %s", jsonData)
+}
+
+// Replaces who the todo is shared with with the subjects entered on one line
+func shareTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
+	id, subjects, ok := readSubjects(reader, "Share with (separated by spaces or commas, blank to stop sharing): ")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout.Duration)
 	defer cancel()
 
-	_, err := client.BulkDeleteTodo(ctx, &pb.BulkDeleteTodoRequest{
-		Ids: ids,
+	res, err := client.ShareTodo(ctx, &pb.ShareTodoRequest{Id: id, SharedWith: subjects})
+	if err != nil {
+		printError("sharing todo", err)
+		return
+	}
+
+	jsonData, _ := json.MarshalIndent(res, "", "  ")
+	fmt.Printf("Shared Todo:\n %s", jsonData)
+}
+
+// readSubjects asks for a todo id and then a list of subjects, ok is false if the id isn't valid
+func readSubjects(reader *bufio.Reader, prompt string) (id string, subjects []string, ok bool) {
+	fmt.Print("Enter TODO ID: ")
+	id, _ = reader.ReadString('\n')
+	id = strings.TrimSpace(id)
+	if !validateUUID(id) {
+		fmt.Printf("uuid %v is not valid!\n", id)
+		return "", nil, false
+	}
+
+	fmt.Print(prompt)
+	line, _ := reader.ReadString('\n')
+	subjects = strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	return id, subjects, true
+}
+
+// Deletes every id entered on one line and reports what happened to each
+func bulkDeleteTodo(client pb.TodoServiceClient, reader *bufio.Reader) {
+
+	fmt.Print("Enter Todo IDs to delete (separated by spaces or commas): ")
+	line, _ := reader.ReadString('\n')
+	ids := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
 	})
+	if len(ids) == 0 {
+		fmt.Println("No ids entered")
+		return
+	}
+
+	// checking for valid IDs
+	for _, id := range ids {
+		if !validateUUID(id) {
+			fmt.Printf("uuid %v is not valid!\n", id)
+			return
+		}
+	}
+
+	req := &pb.BulkDeleteTodoRequest{
+		Ids:            ids,
+		IdempotencyKey: uuid.NewString(),
+	}
 
+	var res *pb.BulkDeleteTodoResponse
+	err := withRetries(func(ctx context.Context) error {
+		var err error
+		res, err = client.BulkDeleteTodo(ctx, req)
+		return err
+	})
+
+	if err != nil {
+		printError("deleting todos", err)
+		return
+	}
+
+	for _, result := range res.GetResults() {
+		switch result.GetStatus() {
+		case pb.DeleteStatus_DELETE_STATUS_DELETED:
+			fmt.Printf("%s: deleted\n", result.GetId())
+		case pb.DeleteStatus_DELETE_STATUS_NOT_FOUND:
+			fmt.Printf("%s: not found\n", result.GetId())
+		case pb.DeleteStatus_DELETE_STATUS_PENDING:
+			fmt.Printf("%s: queued, SafetyCulture is unreachable (%s)\n", result.GetId(), result.GetErrorMessage())
+		default:
+			fmt.Printf("%s: not deleted, %v (%s)\n", result.GetId(), result.GetStatus(), result.GetErrorMessage())
+		}
+	}
+}
+
+// Streams every todo matching the filters, one page at a time
+func listTodos(client pb.TodoServiceClient, reader *bufio.Reader) {
+
+	req := &pb.ListTodosRequest{PageSize: 10}
+
+	fmt.Print("Only completed todos? (y/n, blank for all): ")
+	completed, _ := reader.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(completed)) {
+	case "y":
+		req.Completed = proto.Bool(true)
+	case "n":
+		req.Completed = proto.Bool(false)
+	}
+
+	fmt.Print("Title contains (blank for any): ")
+	title, _ := reader.ReadString('\n')
+	req.TitleContains = strings.TrimSpace(title)
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout.Duration)
+
+		stream, err := client.ListTodos(ctx, req)
+		if err != nil {
+			cancel()
+			printError("listing todos", err)
+			return
+		}
+
+		for {
+			todo, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				cancel()
+				printError("listing todos", err)
+				return
+			}
+			fmt.Printf("[%s] %s - %s (completed: %v)\n", todo.GetId(), todo.GetTitle(), todo.GetDescription(), todo.GetCompleted())
+		}
+
+		// the server only sets the trailer when there is another page
+		next := stream.Trailer().Get("next-page-token")
+		cancel()
+		if len(next) == 0 {
+			return
+		}
+
+		fmt.Print("Press enter for the next page, or q to stop: ")
+		answer, _ := reader.ReadString('\n')
+		if strings.TrimSpace(answer) == "q" {
+			return
+		}
+		req.PageToken = next[0]
+	}
+}
+
+// Asks the server to pull in changes made directly in SafetyCulture
+func syncNow(client pb.TodoServiceClient) {
+
+	// reconciling lists every action in SC, so give it longer than the other calls
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.SyncTimeout.Duration)
+	defer cancel()
+
+	res, err := client.SyncNow(ctx, &pb.SyncNowRequest{})
 	if err != nil {
-		fmt.Printf("Error deleting todo: %v", err)
+		printError("syncing todos", err)
 		return
 	}
 
-	fmt.Printf("Successfully deleted todo item:\n %s", id)
+	fmt.Printf("Imported: %d, updated: %d, deleted: %d\n", res.GetImported(), res.GetUpdated(), res.GetTombstoned())
+	for _, conflict := range res.GetConflicts() {
+		fmt.Printf("Conflict on %s: %s\n", conflict.GetId(), conflict.GetReason())
+	}
 }
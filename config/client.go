@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/tenant"
+)
+
+type Client struct {
+	ServerAddr string    `yaml:"server_addr" toml:"server_addr"`
+	TLS        ClientTLS `yaml:"tls" toml:"tls"`
+	// Token is the API key or JWT sent with every call, for servers that require authentication
+	Token Secret `yaml:"token" toml:"token"`
+	// Tenant picks the tenant on servers without authentication, others take it from Token
+	Tenant string `yaml:"tenant" toml:"tenant"`
+	// SCToken is the caller's own SafetyCulture token, for servers that make changes in SC as the caller
+	SCToken Secret `yaml:"sc_token" toml:"sc_token"`
+	// Timeout bounds each call, SyncTimeout the slower SyncNow
+	Timeout     Duration `yaml:"timeout" toml:"timeout"`
+	SyncTimeout Duration `yaml:"sync_timeout" toml:"sync_timeout"`
+	// Attempts is how many times a call sent with an idempotency key is tried when the server is slow or unreachable
+	Attempts int `yaml:"attempts" toml:"attempts"`
+
+	PrintConfig bool `yaml:"-" toml:"-"`
+}
+
+// ClientTLS connects over TLS when Enabled, which setting any of the files implies.
+// Without CAFile the server is verified against the system roots.
+type ClientTLS struct {
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`
+	CAFile     string `yaml:"ca_file" toml:"ca_file"`
+	CertFile   string `yaml:"cert_file" toml:"cert_file"` // client certificate, for servers that require mutual TLS
+	KeyFile    string `yaml:"key_file" toml:"key_file"`
+	ServerName string `yaml:"server_name" toml:"server_name"` // overrides the name checked in the server's certificate
+}
+
+// DefaultClient is the config used for anything that isn't set
+func DefaultClient() *Client {
+	return &Client{
+		ServerAddr:  "localhost:50051",
+		Timeout:     Duration{time.Second},
+		SyncTimeout: Duration{30 * time.Second},
+		Attempts:    3,
+	}
+}
+
+// LoadClient builds the client config from the defaults, the config file ($TODO_CLIENT_CONFIG),
+// the environment and args
+func LoadClient(args []string) (*Client, error) {
+	cfg := DefaultClient()
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+
+	fs.StringVar(&cfg.ServerAddr, "server-addr", cfg.ServerAddr, "address of the todo server")
+	fs.BoolVar(&cfg.TLS.Enabled, "tls", cfg.TLS.Enabled, "connect over TLS")
+	fs.StringVar(&cfg.TLS.CAFile, "tls-ca", cfg.TLS.CAFile, "verify the server against this CA bundle (PEM) instead of the system roots")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert", cfg.TLS.CertFile, "client certificate (PEM) for mutual TLS")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key", cfg.TLS.KeyFile, "private key of the client certificate (PEM)")
+	fs.StringVar(&cfg.TLS.ServerName, "tls-server-name", cfg.TLS.ServerName, "name to expect in the server's certificate, defaults to the host of server-addr")
+	fs.StringVar(&cfg.Tenant, "tenant", cfg.Tenant, "tenant to work in, on servers without authentication")
+	fs.Var(&cfg.Timeout, "timeout", "how long to wait for each call")
+	fs.Var(&cfg.SyncTimeout, "sync-timeout", "how long to wait for a sync with SafetyCulture")
+	fs.IntVar(&cfg.Attempts, "attempts", cfg.Attempts, "how many times to try a call that is safe to repeat")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the resulting config and exit")
+
+	env := []envVar{
+		{"TODO_SERVER_ADDR", fs.Lookup("server-addr").Value},
+		{"TODO_TLS", fs.Lookup("tls").Value},
+		{"TODO_TLS_CA_FILE", fs.Lookup("tls-ca").Value},
+		{"TODO_TLS_CERT_FILE", fs.Lookup("tls-cert").Value},
+		{"TODO_TLS_KEY_FILE", fs.Lookup("tls-key").Value},
+		{"TODO_TLS_SERVER_NAME", fs.Lookup("tls-server-name").Value},
+		{"TODO_TOKEN", &cfg.Token},
+		{"TODO_TENANT", fs.Lookup("tenant").Value},
+		{"TODO_SC_TOKEN", &cfg.SCToken},
+		{"TODO_TIMEOUT", fs.Lookup("timeout").Value},
+		{"TODO_SYNC_TIMEOUT", fs.Lookup("sync-timeout").Value},
+		{"TODO_ATTEMPTS", fs.Lookup("attempts").Value},
+	}
+
+	if err := load(cfg, fs, env, source{configEnv: "TODO_CLIENT_CONFIG"}, args); err != nil {
+		return nil, err
+	}
+
+	if cfg.TLS.CAFile != "" || cfg.TLS.CertFile != "" {
+		cfg.TLS.Enabled = true
+	}
+	return cfg, nil
+}
+
+// Validate returns every problem with the config at once, keyed by their name in the config file
+func (c *Client) Validate() error {
+	var errs []error
+	if c.ServerAddr == "" {
+		errs = append(errs, errors.New("server_addr must be set"))
+	}
+	errs = append(errs,
+		checkAddr("server_addr", c.ServerAddr),
+		checkPositive("timeout", c.Timeout),
+		checkPositive("sync_timeout", c.SyncTimeout),
+	)
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+	if c.Tenant != "" && !tenant.Valid(c.Tenant) {
+		errs = append(errs, fmt.Errorf("tenant %q must be 1 to 64 letters, digits, '.', '_' or '-'", c.Tenant))
+	}
+	if c.Attempts <= 0 {
+		errs = append(errs, fmt.Errorf("attempts must be positive, got %d", c.Attempts))
+	}
+	return errors.Join(errs...)
+}
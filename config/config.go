@@ -0,0 +1,203 @@
+// Package config loads the settings of the server and the CLI client.
+//
+// Every setting is layered, each source overriding the one before it:
+//
+//  1. the defaults
+//  2. a YAML (.yaml, .yml) or TOML (.toml) file, given with -config or its environment variable
+//  3. environment variables, including the ones in a .env file
+//  4. command line flags
+//
+// Secrets such as the SafetyCulture API key have no flag, so they can't leak
+// through the process list, and are printed as REDACTED by -print-config.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration written as "30s" or "5m" in files, flags and the environment
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+func (d *Duration) Set(value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// Secret is a string that is never printed, fmt and -print-config show REDACTED instead
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Secret) Set(value string) error {
+	*s = Secret(value)
+	return nil
+}
+
+// Reveal returns the secret itself, only call it to hand the secret to whatever needs it
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// envVar binds an environment variable to the setting it overrides
+type envVar struct {
+	name  string
+	value flag.Value
+}
+
+// source says where the config file and the .env file are looked for
+type source struct {
+	configEnv string   // environment variable naming the config file
+	envFiles  []string // .env files tried in order when -env-file isn't given, missing ones are skipped
+}
+
+// load fills cfg, which already holds the defaults, from the config file, the environment and args.
+// fs must already have cfg's flags bound to it.
+func load(cfg any, fs *flag.FlagSet, env []envVar, src source, args []string) error {
+	var path, envFile string
+	fs.StringVar(&path, "config", "", "YAML or TOML config file, defaults to $"+src.configEnv)
+	if src.envFiles != nil {
+		fs.StringVar(&envFile, "env-file", "", "load environment variables from this file, defaults to "+strings.Join(src.envFiles, " or "))
+	}
+
+	// the first parse only finds the files, the flags are applied again once they are read
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := loadEnvFile(envFile, src.envFiles); err != nil {
+		return err
+	}
+	if path == "" {
+		path = os.Getenv(src.configEnv)
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range env {
+		value, ok := os.LookupEnv(v.name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := v.value.Set(value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", v.name, value, err)
+		}
+	}
+
+	return fs.Parse(args)
+}
+
+// loadEnvFile loads path, or else the first of fallbacks that exists. Variables already set win.
+func loadEnvFile(path string, fallbacks []string) error {
+	if path != "" {
+		if err := godotenv.Load(path); err != nil {
+			return fmt.Errorf("failed to load env file: %w", err)
+		}
+		return nil
+	}
+	for _, fallback := range fallbacks {
+		err := godotenv.Load(fallback)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load env file %s: %w", fallback, err)
+		}
+	}
+	return nil
+}
+
+// loadFile decodes the file at path over cfg, keys it doesn't know about are an error
+func loadFile(cfg any, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+		if errors.Is(err, io.EOF) {
+			// an empty file changes nothing
+			err = nil
+		}
+	case ".toml":
+		err = toml.NewDecoder(f).DisallowUnknownFields().Decode(cfg)
+		var strict *toml.StrictMissingError
+		if errors.As(err, &strict) {
+			// the error alone doesn't say which keys
+			keys := make([]string, len(strict.Errors))
+			for i, e := range strict.Errors {
+				keys[i] = strings.Join(e.Key(), ".")
+			}
+			err = fmt.Errorf("unknown keys %s", strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Print writes cfg as YAML, in the same shape a config file takes. Secrets are redacted.
+func Print(w io.Writer, cfg any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// checkAddr reports a problem with a host:port setting, empty addresses are left to the caller
+func checkAddr(key, addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("%s %q must be host:port, e.g. localhost:8080 or :8080", key, addr)
+	}
+	return nil
+}
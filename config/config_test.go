@@ -0,0 +1,255 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/config"
+)
+
+// isolate keeps the test's own environment and .env files out of the config being loaded,
+// the returned args point -env-file at an empty file
+func isolate(t *testing.T) []string {
+	for _, name := range []string{"TODO_CONFIG", "TODO_LISTEN_ADDR", "DEBUG_ADDR", "TODO_DB_PATH", "TODO_STORE", "SC_RATE_BURST", "SC_RATE_LIMIT", "SC_TIMEOUT", "LOG_LEVEL"} {
+		t.Setenv(name, "")
+	}
+	return []string{"-env-file", writeFile(t, ".env", "")}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadServerDefaults(t *testing.T) {
+	cfg, err := config.LoadServer(isolate(t))
+	if err != nil {
+		t.Fatalf("LoadServer: %v", err)
+	}
+	if cfg.ListenAddr != ":50051" || cfg.Store.Backend != config.StoreMemory || cfg.SafetyCulture.TokenPolicy != config.TokenShared {
+		t.Fatalf("got %+v, want the defaults", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("the defaults don't validate: %v", err)
+	}
+}
+
+func TestLoadServerPrecedence(t *testing.T) {
+	const yamlFile = `
+listen_addr: ":1"
+debug_addr: "localhost:1"
+store:
+  path: file.db
+safetyculture:
+  rate_burst: 7
+  timeout: 3s
+`
+	const tomlFile = `
+listen_addr = ":1"
+debug_addr = "localhost:1"
+
+[store]
+path = "file.db"
+
+[safetyculture]
+rate_burst = 7
+timeout = "3s"
+`
+	tests := []struct {
+		name       string
+		file, ext  string
+		fromEnvVar bool // name the file with $TODO_CONFIG instead of -config
+	}{
+		{name: "YAML", file: yamlFile, ext: ".yaml"},
+		{name: "YML", file: yamlFile, ext: ".yml"},
+		{name: "TOML", file: tomlFile, ext: ".toml"},
+		{name: "file named by the environment", file: yamlFile, ext: ".yaml", fromEnvVar: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := isolate(t)
+			path := writeFile(t, "todo"+tt.ext, tt.file)
+			if tt.fromEnvVar {
+				t.Setenv("TODO_CONFIG", path)
+			} else {
+				args = append(args, "-config", path)
+			}
+			// debug_addr and store.path come from the environment over the file, store.path from a flag over both
+			t.Setenv("DEBUG_ADDR", "localhost:2")
+			t.Setenv("TODO_DB_PATH", "env.db")
+			args = append(args, "-db-path", "flag.db")
+
+			cfg, err := config.LoadServer(args)
+			if err != nil {
+				t.Fatalf("LoadServer: %v", err)
+			}
+			checks := []struct {
+				setting   string
+				got, want any
+			}{
+				{"listen_addr from the file", cfg.ListenAddr, ":1"},
+				{"safetyculture.rate_burst from the file", cfg.SafetyCulture.RateBurst, 7},
+				{"safetyculture.timeout from the file", cfg.SafetyCulture.Timeout.Duration, 3 * time.Second},
+				{"safetyculture.rate_limit left at its default", cfg.SafetyCulture.RateLimit, config.DefaultServer().SafetyCulture.RateLimit},
+				{"debug_addr from the environment", cfg.DebugAddr, "localhost:2"},
+				{"store.path from the flag", cfg.Store.Path, "flag.db"},
+				{"store.backend following store.path", cfg.Store.Backend, config.StoreSQLite},
+			}
+			for _, c := range checks {
+				if c.got != c.want {
+					t.Errorf("%s: got %v, want %v", c.setting, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadServerRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // file name and content, when the test needs one
+		content string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "unknown YAML key", file: "todo.yaml", content: "listen_adr: \":1\"\n", wantErr: "listen_adr"},
+		{name: "unknown nested YAML key", file: "todo.yaml", content: "safetyculture:\n  rate_limt: 1\n", wantErr: "rate_limt"},
+		{name: "unknown TOML key", file: "todo.toml", content: "listen_adr = \":1\"\n", wantErr: "listen_adr"},
+		{name: "unknown nested TOML key", file: "todo.toml", content: "[safetyculture]\nrate_limt = 1\n", wantErr: "rate_limt"},
+		{name: "file that is neither YAML nor TOML", file: "todo.json", content: "{}", wantErr: "must end in .yaml, .yml or .toml"},
+		{name: "malformed duration in the file", file: "todo.yaml", content: "idempotency_ttl: soon\n", wantErr: `invalid duration "soon"`},
+		{name: "malformed environment variable", env: map[string]string{"SC_TIMEOUT": "soon"}, wantErr: `invalid SC_TIMEOUT "soon"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := isolate(t)
+			if tt.file != "" {
+				args = append(args, "-config", writeFile(t, tt.file, tt.content))
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			_, err := config.LoadServer(args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *config.Server)
+		wantErr string
+	}{
+		{"no listen_addr", func(c *config.Server) { c.ListenAddr = "" }, "listen_addr must be set"},
+		{"listen_addr without a port", func(c *config.Server) { c.ListenAddr = "localhost" }, `listen_addr "localhost" must be host:port`},
+		{"debug_addr without a port", func(c *config.Server) { c.DebugAddr = "localhost" }, `debug_addr "localhost" must be host:port`},
+		{"webhook_addr without a port", func(c *config.Server) { c.WebhookAddr = "localhost"; c.SafetyCulture.WebhookSecret = "s" }, `webhook_addr "localhost" must be host:port`},
+		{"TLS certificate without its key", func(c *config.Server) { c.TLS.CertFile = "cert.pem" }, "tls.cert_file and tls.key_file must be set together"},
+		{"TLS key without its certificate", func(c *config.Server) { c.TLS.KeyFile = "key.pem" }, "tls.cert_file and tls.key_file must be set together"},
+		{"client CA without TLS", func(c *config.Server) { c.TLS.ClientCAFile = "ca.pem" }, "tls.client_ca_file needs tls.cert_file"},
+		{"JWT issuer without a JWT key", func(c *config.Server) { c.Auth.Issuer = "issuer" }, "auth.jwt_issuer and auth.jwt_audience need"},
+		{"JWT audience without a JWT key", func(c *config.Server) { c.Auth.APIKeysFile = "keys.yaml"; c.Auth.Audience = "todo" }, "auth.jwt_issuer and auth.jwt_audience need"},
+		{"policy without authentication", func(c *config.Server) { c.Auth.PolicyFile = "policy.yaml" }, "auth.policy_file needs authentication"},
+		{"unknown log level", func(c *config.Server) { c.Log.Level = "warn" }, `log.level "warn" must be debug or info`},
+		{"unknown log format", func(c *config.Server) { c.Log.Format = "xml" }, `log.format "xml" must be text or json`},
+		{"base URL without a scheme", func(c *config.Server) { c.SafetyCulture.BaseURL = "api.example.com" }, "safetyculture.base_url"},
+		{"base URL that isn't HTTP", func(c *config.Server) { c.SafetyCulture.BaseURL = "ftp://api.example.com" }, "must be an http or https URL"},
+		{"unknown token policy", func(c *config.Server) { c.SafetyCulture.TokenPolicy = "mine" }, `safetyculture.token_policy "mine" must be shared, passthrough or required`},
+		{"passthrough without an API key", func(c *config.Server) { c.SafetyCulture.TokenPolicy = config.TokenPassthrough }, "safetyculture.api_key must be set for the passthrough token policy"},
+		{"required without an API key", func(c *config.Server) { c.SafetyCulture.TokenPolicy = config.TokenRequired }, "safetyculture.api_key must be set for the required token policy"},
+		{"negative timeout", func(c *config.Server) { c.SafetyCulture.Timeout.Duration = -time.Second }, "safetyculture.timeout must not be negative"},
+		{"zero rate limit", func(c *config.Server) { c.SafetyCulture.RateLimit = 0 }, "safetyculture.rate_limit must be positive"},
+		{"zero rate burst", func(c *config.Server) { c.SafetyCulture.RateBurst = 0 }, "safetyculture.rate_burst must be positive"},
+		{"zero breaker failures", func(c *config.Server) { c.SafetyCulture.BreakerFailures = 0 }, "safetyculture.breaker_failures must be positive"},
+		{"zero breaker cool down", func(c *config.Server) { c.SafetyCulture.BreakerCoolDown.Duration = 0 }, "safetyculture.breaker_cooldown must be positive"},
+		{"webhooks without a secret", func(c *config.Server) { c.WebhookAddr = ":8082" }, "safetyculture.webhook_secret must be set when webhook_addr is"},
+		{"empty SC user id", func(c *config.Server) { c.SafetyCulture.UserIDs = map[string]string{"alice": ""} }, "safetyculture.user_ids.alice must not be empty"},
+		{"sqlite without a path", func(c *config.Server) { c.Store.Backend = config.StoreSQLite }, "store.path must be set for the sqlite store"},
+		{"unknown store", func(c *config.Server) { c.Store.Backend = "postgres" }, `store.backend "postgres" must be memory or sqlite`},
+		{"negative max todos", func(c *config.Server) { c.Tenants.MaxTodos = -1 }, "tenants.max_todos must not be negative"},
+		{"invalid tenant in max todos per tenant", func(c *config.Server) { c.Tenants.MaxTodosPerTenant = map[string]int{"team a": 1} }, `"team a" isn't a valid tenant id`},
+		{"negative max todos per tenant", func(c *config.Server) { c.Tenants.MaxTodosPerTenant = map[string]int{"team-a": -1} }, "tenants.max_todos_per_tenant.team-a must not be negative"},
+		{"zero idempotency TTL", func(c *config.Server) { c.IdempotencyTTL.Duration = 0 }, "idempotency_ttl must be positive"},
+		{"zero reconcile interval", func(c *config.Server) { c.ReconcileInterval.Duration = 0 }, "reconcile_interval must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultServer()
+			tt.change(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerValidateReportsEveryProblem(t *testing.T) {
+	cfg := config.DefaultServer()
+	cfg.ListenAddr = ""
+	cfg.Log.Level = "warn"
+	cfg.Store.Backend = "postgres"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted a broken config")
+	}
+	if lines := strings.Split(err.Error(), "\n"); len(lines) != 3 {
+		t.Fatalf("got %d problems, want 3:\n%v", len(lines), err)
+	}
+}
+
+func TestClientValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *config.Client)
+		wantErr string
+	}{
+		{"no server_addr", func(c *config.Client) { c.ServerAddr = "" }, "server_addr must be set"},
+		{"server_addr without a port", func(c *config.Client) { c.ServerAddr = "localhost" }, `server_addr "localhost" must be host:port`},
+		{"zero timeout", func(c *config.Client) { c.Timeout.Duration = 0 }, "timeout must be positive"},
+		{"zero sync timeout", func(c *config.Client) { c.SyncTimeout.Duration = 0 }, "sync_timeout must be positive"},
+		{"client certificate without its key", func(c *config.Client) { c.TLS.CertFile = "cert.pem" }, "tls.cert_file and tls.key_file must be set together"},
+		{"invalid tenant", func(c *config.Client) { c.Tenant = "team a" }, `tenant "team a" must be 1 to 64 letters`},
+		{"zero attempts", func(c *config.Client) { c.Attempts = 0 }, "attempts must be positive"},
+	}
+	if err := config.DefaultClient().Validate(); err != nil {
+		t.Fatalf("the defaults don't validate: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultClient()
+			tt.change(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := config.DefaultServer()
+	cfg.SafetyCulture.APIKey = "sc-api-key"
+	cfg.SafetyCulture.WebhookSecret = "webhook-secret"
+	var out bytes.Buffer
+	if err := config.Print(&out, cfg); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if strings.Contains(out.String(), "sc-api-key") || strings.Contains(out.String(), "webhook-secret") {
+		t.Fatalf("secrets were printed:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "api_key: REDACTED") {
+		t.Fatalf("api_key isn't shown as REDACTED:\n%s", out.String())
+	}
+}
@@ -0,0 +1,319 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/idempotency"
+	"github.com/jerryhong21/todo-grpc/reconcile"
+	"github.com/jerryhong21/todo-grpc/tenant"
+)
+
+// SafetyCulture token policies, whose token the server calls SC with for each request
+const (
+	TokenShared      = "shared"      // always the server's api_key
+	TokenPassthrough = "passthrough" // the caller's token when they send one, else api_key
+	TokenRequired    = "required"    // the caller's token, calls without one are rejected
+)
+
+// Store backends
+const (
+	StoreMemory = "memory"
+	StoreSQLite = "sqlite"
+)
+
+type Server struct {
+	ListenAddr  string `yaml:"listen_addr" toml:"listen_addr"`
+	DebugAddr   string `yaml:"debug_addr" toml:"debug_addr"`     // serves /debug/vars, off when empty
+	WebhookAddr string `yaml:"webhook_addr" toml:"webhook_addr"` // receives SC webhooks, off when empty
+
+	TLS           ServerTLS     `yaml:"tls" toml:"tls"`
+	Auth          Auth          `yaml:"auth" toml:"auth"`
+	Log           Log           `yaml:"log" toml:"log"`
+	SafetyCulture SafetyCulture `yaml:"safetyculture" toml:"safetyculture"`
+	Store         Store         `yaml:"store" toml:"store"`
+	Tenants       Tenants       `yaml:"tenants" toml:"tenants"`
+
+	IdempotencyTTL    Duration `yaml:"idempotency_ttl" toml:"idempotency_ttl"`
+	ReconcileInterval Duration `yaml:"reconcile_interval" toml:"reconcile_interval"`
+
+	// PrintConfig asks for the config to be printed instead of starting the server
+	PrintConfig bool `yaml:"-" toml:"-"`
+}
+
+// ServerTLS turns on TLS when CertFile is set, and mutual TLS when ClientCAFile is too.
+// The files are reloaded when they change.
+type ServerTLS struct {
+	CertFile     string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile      string `yaml:"key_file" toml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file" toml:"client_ca_file"`
+}
+
+// Auth requires every RPC to carry an API key or JWT once any of the files is set
+type Auth struct {
+	APIKeysFile     string `yaml:"api_keys_file" toml:"api_keys_file"`
+	HS256SecretFile string `yaml:"jwt_hs256_secret_file" toml:"jwt_hs256_secret_file"`
+	JWKSFile        string `yaml:"jwt_jwks_file" toml:"jwt_jwks_file"`
+	Issuer          string `yaml:"jwt_issuer" toml:"jwt_issuer"`
+	Audience        string `yaml:"jwt_audience" toml:"jwt_audience"`
+	// PolicyFile limits each caller to the RPCs their roles allow, every caller may call every RPC when empty
+	PolicyFile string `yaml:"policy_file" toml:"policy_file"`
+}
+
+// Enabled reports whether callers have to authenticate
+func (a Auth) Enabled() bool {
+	return a.APIKeysFile != "" || a.HS256SecretFile != "" || a.JWKSFile != ""
+}
+
+type Log struct {
+	Level  string `yaml:"level" toml:"level"`   // info, or debug to also log every RPC
+	Format string `yaml:"format" toml:"format"` // text or json
+}
+
+type SafetyCulture struct {
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+	APIKey  Secret `yaml:"api_key" toml:"api_key"`
+	// TokenPolicy is shared, passthrough or required. It decides whether callers' own SC tokens
+	// are used for the todos they create, get, update and delete
+	TokenPolicy string `yaml:"token_policy" toml:"token_policy"`
+	// Timeout bounds one SC call including its retries, 0 waits as long as the RPC allows
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+
+	RateLimit float64 `yaml:"rate_limit" toml:"rate_limit"` // requests per second
+	RateBurst int     `yaml:"rate_burst" toml:"rate_burst"`
+
+	BreakerFailures int      `yaml:"breaker_failures" toml:"breaker_failures"`
+	BreakerCoolDown Duration `yaml:"breaker_cooldown" toml:"breaker_cooldown"`
+	// ServeLocalWhenOpen lets GetTodo answer from the store while the breaker is open
+	ServeLocalWhenOpen bool `yaml:"serve_local_when_open" toml:"serve_local_when_open"`
+
+	WebhookSecret Secret `yaml:"webhook_secret" toml:"webhook_secret"`
+
+	// UserIDs maps callers' subjects to SC user ids for action assignees, only settable in the config file.
+	// Subjects that aren't listed are sent as they are, for issuers whose sub already is the SC user id.
+	UserIDs map[string]string `yaml:"user_ids" toml:"user_ids"`
+}
+
+type Store struct {
+	// Backend is memory or sqlite, left empty it is sqlite when Path is set
+	Backend string `yaml:"backend" toml:"backend"`
+	Path    string `yaml:"path" toml:"path"`
+}
+
+// Tenants caps how many todos each tenant can keep, 0 is no cap
+type Tenants struct {
+	MaxTodos int `yaml:"max_todos" toml:"max_todos"`
+	// MaxTodosPerTenant overrides MaxTodos for the tenants listed, only settable in the config file
+	MaxTodosPerTenant map[string]int `yaml:"max_todos_per_tenant" toml:"max_todos_per_tenant"`
+}
+
+// DefaultServer is the config used for anything that isn't set
+func DefaultServer() *Server {
+	return &Server{
+		ListenAddr: ":50051",
+		Log:        Log{Level: "info", Format: "text"},
+		SafetyCulture: SafetyCulture{
+			BaseURL:         external.DefaultBaseURL,
+			TokenPolicy:     TokenShared,
+			RateLimit:       external.DefaultRateLimit,
+			RateBurst:       external.DefaultRateBurst,
+			BreakerFailures: external.DefaultBreakerFailures,
+			BreakerCoolDown: Duration{external.DefaultBreakerCoolDown},
+		},
+		IdempotencyTTL:    Duration{idempotency.DefaultTTL},
+		ReconcileInterval: Duration{reconcile.DefaultInterval},
+	}
+}
+
+// LoadServer builds the server config from the defaults, the config file ($TODO_CONFIG),
+// the environment (and a .env file in the working directory or its parent) and args
+func LoadServer(args []string) (*Server, error) {
+	cfg := DefaultServer()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+
+	fs.StringVar(&cfg.ListenAddr, "listen-addr", cfg.ListenAddr, "address the gRPC server listens on")
+	fs.StringVar(&cfg.DebugAddr, "debug-addr", cfg.DebugAddr, "serve /debug/vars here, e.g. localhost:6060")
+	fs.StringVar(&cfg.WebhookAddr, "webhook-addr", cfg.WebhookAddr, "receive SafetyCulture webhooks here, e.g. :8082")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert", cfg.TLS.CertFile, "serve TLS with this certificate (PEM)")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key", cfg.TLS.KeyFile, "private key of the TLS certificate (PEM)")
+	fs.StringVar(&cfg.TLS.ClientCAFile, "tls-client-ca", cfg.TLS.ClientCAFile, "require client certificates signed by this CA bundle (PEM)")
+	fs.StringVar(&cfg.Auth.APIKeysFile, "auth-api-keys", cfg.Auth.APIKeysFile, "accept the API keys listed in this YAML file")
+	fs.StringVar(&cfg.Auth.HS256SecretFile, "auth-jwt-secret", cfg.Auth.HS256SecretFile, "accept HS256 JWTs signed with the secret in this file")
+	fs.StringVar(&cfg.Auth.JWKSFile, "auth-jwks", cfg.Auth.JWKSFile, "accept RS256 JWTs signed by a key in this JWKS file")
+	fs.StringVar(&cfg.Auth.Issuer, "auth-jwt-issuer", cfg.Auth.Issuer, "required iss claim of JWTs")
+	fs.StringVar(&cfg.Auth.Audience, "auth-jwt-audience", cfg.Auth.Audience, "required aud claim of JWTs")
+	fs.StringVar(&cfg.Auth.PolicyFile, "auth-policy", cfg.Auth.PolicyFile, "only allow the RPCs callers' roles grant in this YAML policy file")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "info, or debug to log every RPC")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "text or json")
+	fs.StringVar(&cfg.SafetyCulture.BaseURL, "sc-base-url", cfg.SafetyCulture.BaseURL, "SafetyCulture API to mirror todos into")
+	fs.StringVar(&cfg.SafetyCulture.TokenPolicy, "sc-token-policy", cfg.SafetyCulture.TokenPolicy, "shared, passthrough or required: whether callers' own SafetyCulture tokens are used")
+	fs.Var(&cfg.SafetyCulture.Timeout, "sc-timeout", "give up on a SafetyCulture call after this long, 0 for no limit")
+	fs.Float64Var(&cfg.SafetyCulture.RateLimit, "sc-rate-limit", cfg.SafetyCulture.RateLimit, "SafetyCulture requests per second")
+	fs.IntVar(&cfg.SafetyCulture.RateBurst, "sc-rate-burst", cfg.SafetyCulture.RateBurst, "SafetyCulture request burst size")
+	fs.IntVar(&cfg.SafetyCulture.BreakerFailures, "sc-breaker-failures", cfg.SafetyCulture.BreakerFailures, "failures in a row that open the circuit breaker")
+	fs.Var(&cfg.SafetyCulture.BreakerCoolDown, "sc-breaker-cooldown", "how long the circuit breaker stays open")
+	fs.BoolVar(&cfg.SafetyCulture.ServeLocalWhenOpen, "sc-serve-local-when-open", cfg.SafetyCulture.ServeLocalWhenOpen, "answer GetTodo from the store while the circuit breaker is open")
+	fs.StringVar(&cfg.Store.Backend, "store", cfg.Store.Backend, "memory or sqlite")
+	fs.StringVar(&cfg.Store.Path, "db-path", cfg.Store.Path, "SQLite database file")
+	fs.IntVar(&cfg.Tenants.MaxTodos, "tenant-max-todos", cfg.Tenants.MaxTodos, "most todos a tenant can have, 0 for no limit")
+	fs.Var(&cfg.IdempotencyTTL, "idempotency-ttl", "how long responses are kept for idempotency key replays")
+	fs.Var(&cfg.ReconcileInterval, "reconcile-interval", "how often todos are reconciled with SafetyCulture")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the resulting config and exit")
+
+	// the env names predate the config file, keep them working
+	env := []envVar{
+		{"TODO_LISTEN_ADDR", fs.Lookup("listen-addr").Value},
+		{"DEBUG_ADDR", fs.Lookup("debug-addr").Value},
+		{"WEBHOOK_ADDR", fs.Lookup("webhook-addr").Value},
+		{"TLS_CERT_FILE", fs.Lookup("tls-cert").Value},
+		{"TLS_KEY_FILE", fs.Lookup("tls-key").Value},
+		{"TLS_CLIENT_CA_FILE", fs.Lookup("tls-client-ca").Value},
+		{"AUTH_API_KEYS_FILE", fs.Lookup("auth-api-keys").Value},
+		{"AUTH_JWT_SECRET_FILE", fs.Lookup("auth-jwt-secret").Value},
+		{"AUTH_JWKS_FILE", fs.Lookup("auth-jwks").Value},
+		{"AUTH_JWT_ISSUER", fs.Lookup("auth-jwt-issuer").Value},
+		{"AUTH_JWT_AUDIENCE", fs.Lookup("auth-jwt-audience").Value},
+		{"AUTH_POLICY_FILE", fs.Lookup("auth-policy").Value},
+		{"LOG_LEVEL", fs.Lookup("log-level").Value},
+		{"LOG_FORMAT", fs.Lookup("log-format").Value},
+		{"SC_BASE_URL", fs.Lookup("sc-base-url").Value},
+		{"SC_API_KEY", &cfg.SafetyCulture.APIKey},
+		{"SC_TOKEN_POLICY", fs.Lookup("sc-token-policy").Value},
+		{"SC_TIMEOUT", fs.Lookup("sc-timeout").Value},
+		{"SC_RATE_LIMIT", fs.Lookup("sc-rate-limit").Value},
+		{"SC_RATE_BURST", fs.Lookup("sc-rate-burst").Value},
+		{"SC_BREAKER_FAILURES", fs.Lookup("sc-breaker-failures").Value},
+		{"SC_BREAKER_COOLDOWN", fs.Lookup("sc-breaker-cooldown").Value},
+		{"SC_BREAKER_SERVE_LOCAL", fs.Lookup("sc-serve-local-when-open").Value},
+		{"SC_WEBHOOK_SECRET", &cfg.SafetyCulture.WebhookSecret},
+		{"TODO_STORE", fs.Lookup("store").Value},
+		{"TODO_DB_PATH", fs.Lookup("db-path").Value},
+		{"TENANT_MAX_TODOS", fs.Lookup("tenant-max-todos").Value},
+		{"IDEMPOTENCY_TTL", fs.Lookup("idempotency-ttl").Value},
+		{"RECONCILE_INTERVAL", fs.Lookup("reconcile-interval").Value},
+	}
+
+	src := source{configEnv: "TODO_CONFIG", envFiles: []string{".env", "../.env"}}
+	if err := load(cfg, fs, env, src, args); err != nil {
+		return nil, err
+	}
+
+	if cfg.Store.Backend == "" {
+		cfg.Store.Backend = StoreMemory
+		if cfg.Store.Path != "" {
+			cfg.Store.Backend = StoreSQLite
+		}
+	}
+	return cfg, nil
+}
+
+// Validate returns every problem with the config at once, keyed by their name in the config file
+func (c *Server) Validate() error {
+	var errs []error
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listen_addr must be set"))
+	}
+	errs = append(errs,
+		checkAddr("listen_addr", c.ListenAddr),
+		checkAddr("debug_addr", c.DebugAddr),
+		checkAddr("webhook_addr", c.WebhookAddr),
+	)
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+	if c.TLS.ClientCAFile != "" && c.TLS.CertFile == "" {
+		errs = append(errs, errors.New("tls.client_ca_file needs tls.cert_file, mutual TLS is on top of TLS"))
+	}
+
+	if (c.Auth.Issuer != "" || c.Auth.Audience != "") && c.Auth.HS256SecretFile == "" && c.Auth.JWKSFile == "" {
+		errs = append(errs, errors.New("auth.jwt_issuer and auth.jwt_audience need auth.jwt_hs256_secret_file or auth.jwt_jwks_file"))
+	}
+	if c.Auth.PolicyFile != "" && !c.Auth.Enabled() {
+		errs = append(errs, errors.New("auth.policy_file needs authentication, set auth.api_keys_file, auth.jwt_hs256_secret_file or auth.jwt_jwks_file"))
+	}
+
+	switch c.Log.Level {
+	case "debug", "info":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q must be debug or info", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log.format %q must be text or json", c.Log.Format))
+	}
+
+	sc := c.SafetyCulture
+	if u, err := url.Parse(sc.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("safetyculture.base_url %q must be an http or https URL", sc.BaseURL))
+	}
+	switch sc.TokenPolicy {
+	case TokenShared, TokenPassthrough, TokenRequired:
+	default:
+		errs = append(errs, fmt.Errorf("safetyculture.token_policy %q must be shared, passthrough or required", sc.TokenPolicy))
+	}
+	// callers' tokens only cover their own requests, reconciliation and webhooks still need the shared key
+	if sc.TokenPolicy != TokenShared && sc.APIKey == "" {
+		errs = append(errs, fmt.Errorf("safetyculture.api_key must be set for the %s token policy, reconciliation and webhooks read SafetyCulture with it", sc.TokenPolicy))
+	}
+	if sc.Timeout.Duration < 0 {
+		errs = append(errs, fmt.Errorf("safetyculture.timeout must not be negative, got %s", sc.Timeout))
+	}
+	if sc.RateLimit <= 0 {
+		errs = append(errs, fmt.Errorf("safetyculture.rate_limit must be positive, got %v", sc.RateLimit))
+	}
+	if sc.RateBurst <= 0 {
+		errs = append(errs, fmt.Errorf("safetyculture.rate_burst must be positive, got %d", sc.RateBurst))
+	}
+	if sc.BreakerFailures <= 0 {
+		errs = append(errs, fmt.Errorf("safetyculture.breaker_failures must be positive, got %d", sc.BreakerFailures))
+	}
+	errs = append(errs, checkPositive("safetyculture.breaker_cooldown", sc.BreakerCoolDown))
+	if c.WebhookAddr != "" && sc.WebhookSecret == "" {
+		errs = append(errs, errors.New("safetyculture.webhook_secret must be set when webhook_addr is"))
+	}
+	for subject, userID := range sc.UserIDs {
+		if userID == "" {
+			errs = append(errs, fmt.Errorf("safetyculture.user_ids.%s must not be empty", subject))
+		}
+	}
+
+	switch c.Store.Backend {
+	case StoreMemory:
+	case StoreSQLite:
+		if c.Store.Path == "" {
+			errs = append(errs, errors.New("store.path must be set for the sqlite store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("store.backend %q must be memory or sqlite", c.Store.Backend))
+	}
+
+	if c.Tenants.MaxTodos < 0 {
+		errs = append(errs, fmt.Errorf("tenants.max_todos must not be negative, got %d", c.Tenants.MaxTodos))
+	}
+	for name, limit := range c.Tenants.MaxTodosPerTenant {
+		if !tenant.Valid(name) {
+			errs = append(errs, fmt.Errorf("tenants.max_todos_per_tenant: %q isn't a valid tenant id", name))
+		}
+		if limit < 0 {
+			errs = append(errs, fmt.Errorf("tenants.max_todos_per_tenant.%s must not be negative, got %d", name, limit))
+		}
+	}
+
+	errs = append(errs,
+		checkPositive("idempotency_ttl", c.IdempotencyTTL),
+		checkPositive("reconcile_interval", c.ReconcileInterval),
+	)
+	return errors.Join(errs...)
+}
+
+func checkPositive(key string, d Duration) error {
+	if d.Duration <= 0 {
+		return fmt.Errorf("%s must be positive, got %s", key, d)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// Writes a throwaway CA plus server and client certificates for trying out TLS locally:
+//
+//	go run ./devcerts -out certs
+//	go run ./server -tls-cert certs/server.pem -tls-key certs/server-key.pem -tls-client-ca certs/ca.pem
+//	go run ./client -tls-ca certs/ca.pem -tls-cert certs/client.pem -tls-key certs/client-key.pem
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/jerryhong21/todo-grpc/tlsconfig"
+)
+
+func main() {
+	out := flag.String("out", "certs", "directory to write the certificates and keys to")
+	hosts := flag.String("hosts", "localhost,127.0.0.1,::1", "comma separated names and IPs the server certificate is valid for")
+	client := flag.String("client", "dev-client", "common name of the client certificate")
+	flag.Parse()
+
+	files, err := tlsconfig.WriteDevCerts(*out, strings.Split(*hosts, ","), *client)
+	if err != nil {
+		log.Fatalf("Failed to write certificates: %v", err)
+	}
+	log.Printf("Wrote CA %s, server certificate %s and client certificate %s", files.CAFile, files.ServerCertFile, files.ClientCertFile)
+}
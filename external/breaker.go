@@ -0,0 +1,169 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults for the SafetyCulture circuit breaker
+const (
+	DefaultBreakerFailures = 5
+	DefaultBreakerCoolDown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without calling SafetyCulture while the circuit breaker is open
+var ErrCircuitOpen = status.Error(codes.Unavailable, "SafetyCulture API is unavailable, circuit breaker is open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// CircuitBreaker stops calling SafetyCulture after repeated failures so handlers fail fast
+// instead of every request waiting out its deadline.
+//
+// After FailureThreshold consecutive failures the circuit opens and requests are rejected with
+// ErrCircuitOpen. Once CoolDown has passed a single probe request is let through (half-open),
+// its result decides whether the circuit closes again or stays open for another cool-down.
+type CircuitBreaker struct {
+	FailureThreshold int
+	CoolDown         time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool   // a half-open probe is in flight
+	epoch    uint64 // counts the times the circuit opened or closed, to spot results of requests from before
+}
+
+func NewCircuitBreaker(failureThreshold int, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		CoolDown:         coolDown,
+		state:            BreakerClosed,
+	}
+}
+
+// CircuitBreakerState is a snapshot of the breaker for diagnostics
+type CircuitBreakerState struct {
+	State    BreakerState `json:"state"`
+	Failures int          `json:"failures"`
+	OpenedAt time.Time    `json:"opened_at"` // zero while closed
+}
+
+func (b *CircuitBreaker) State() CircuitBreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return CircuitBreakerState{State: b.state, Failures: b.failures, OpenedAt: b.openedAt}
+}
+
+// admission is what allow hands a request it lets through, the request passes it back with its result
+type admission struct {
+	epoch uint64 // the breaker's epoch when the request was let through
+	probe bool   // the request is the half-open probe
+}
+
+// allow reports whether a request may go through, moving an open circuit to half-open once cooled down
+func (b *CircuitBreaker) allow() (admission, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.CoolDown {
+			return admission{}, false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return admission{epoch: b.epoch, probe: true}, true
+	case BreakerHalfOpen:
+		// only one probe at a time, everyone else keeps failing fast
+		if b.probing {
+			return admission{}, false
+		}
+		b.probing = true
+		return admission{epoch: b.epoch, probe: true}, true
+	default:
+		return admission{epoch: b.epoch}, true
+	}
+}
+
+func (b *CircuitBreaker) record(a admission, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if a.probe {
+		b.probing = false
+	} else if a.epoch != b.epoch || b.state != BreakerClosed {
+		// let through before the circuit last opened, only the probe decides whether it closes
+		return
+	}
+
+	if success {
+		if b.state != BreakerClosed {
+			b.epoch++
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.FailureThreshold {
+		if b.state == BreakerClosed {
+			b.epoch++
+		}
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// release gives up a request's admission without counting a result, e.g. when the caller canceled.
+// Only the probe holds anything to give up.
+func (b *CircuitBreaker) release(a admission) {
+	if !a.probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// BreakerTransport wraps the whole retrying call, so one logical request counts as one failure
+type BreakerTransport struct {
+	Base    http.RoundTripper
+	Breaker *CircuitBreaker
+}
+
+func (t *BreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	admitted, ok := t.Breaker.allow()
+	if !ok {
+		return nil, ErrCircuitOpen
+	}
+
+	res, err := t.Base.RoundTrip(req)
+
+	switch {
+	case err != nil && (errors.Is(req.Context().Err(), context.Canceled) || errors.Is(err, errRateLimited)):
+		// the caller hung up or our own limiter gave up, that says nothing about SC's health.
+		// running out of time does, a hanging SC only ever shows up as deadlines and client timeouts
+		t.Breaker.release(admitted)
+	case err != nil:
+		t.Breaker.record(admitted, false)
+	default:
+		// 4xx means SC is up and answering, only server errors count against it
+		t.Breaker.record(admitted, res.StatusCode < 500)
+	}
+	return res, err
+}
@@ -0,0 +1,66 @@
+package external
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Hour)
+
+	// let through while closed, still running when the circuit opens
+	slow, ok := b.allow()
+	if !ok {
+		t.Fatal("closed breaker refused a request")
+	}
+	for i := 0; i < 2; i++ {
+		a, _ := b.allow()
+		b.record(a, false)
+	}
+	if state := b.State().State; state != BreakerOpen {
+		t.Fatalf("breaker is %s, want open", state)
+	}
+
+	b.record(slow, true)
+	if state := b.State().State; state != BreakerOpen {
+		t.Fatalf("a request from before the circuit opened moved it to %s", state)
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	b := NewCircuitBreaker(1, time.Millisecond)
+
+	before, _ := b.allow()
+	failed, _ := b.allow()
+	b.record(failed, false)
+	time.Sleep(2 * time.Millisecond)
+
+	probe, ok := b.allow()
+	if !ok || !probe.probe {
+		t.Fatal("cooled down breaker didn't let a probe through")
+	}
+	if _, ok := b.allow(); ok {
+		t.Fatal("half-open breaker let a second request through alongside the probe")
+	}
+
+	// only the probe holds the half-open slot
+	b.release(before)
+	if _, ok := b.allow(); ok {
+		t.Fatal("releasing a request from before the circuit opened freed the probe slot")
+	}
+	b.record(before, true)
+	if state := b.State().State; state != BreakerHalfOpen {
+		t.Fatalf("a request from before the circuit opened moved it to %s", state)
+	}
+
+	b.record(probe, true)
+	if state := b.State().State; state != BreakerClosed {
+		t.Fatalf("breaker is %s after a successful probe, want closed", state)
+	}
+
+	// and a stale failure can't count against the circuit that just closed
+	b.record(before, false)
+	if state := b.State(); state.Failures != 0 {
+		t.Fatalf("a request from before the circuit closed counted as a failure: %+v", state)
+	}
+}
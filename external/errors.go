@@ -0,0 +1,155 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain identifies SafetyCulture as the source in ErrorInfo details
+const errorDomain = "api.safetyculture.io"
+
+// ScErrorResponse is the error envelope returned by the SafetyCulture API
+type ScErrorResponse struct {
+	Code    int               `json:"code"`
+	Message string            `json:"message"`
+	Details []json.RawMessage `json:"details"`
+}
+
+// APIError is returned when the SafetyCulture API rejects a request.
+//
+// It implements GRPCStatus, so handlers can return it as is and clients
+// receive a matching gRPC code (NotFound, PermissionDenied, ...) instead of Unknown.
+type APIError struct {
+	HTTPStatus int
+	ScErrorResponse
+	// RetryAfter is how long SC asked us to wait, zero when it didn't say
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("SafetyCulture API error (HTTP %d, code %d): %s", e.HTTPStatus, e.Code, e.Message)
+}
+
+// GRPCCode picks the gRPC code for the error.
+// SC's envelope usually carries a gRPC code already, otherwise the HTTP status is mapped.
+func (e *APIError) GRPCCode() codes.Code {
+	if e.Code > int(codes.OK) && e.Code <= int(codes.Unauthenticated) {
+		return codes.Code(e.Code)
+	}
+	return httpStatusToCode(e.HTTPStatus)
+}
+
+func (e *APIError) GRPCStatus() *status.Status {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.HTTPStatus)
+	}
+	st := status.New(e.GRPCCode(), "SafetyCulture API: "+message)
+
+	info := &errdetails.ErrorInfo{
+		Reason: "SAFETYCULTURE_API_ERROR",
+		Domain: errorDomain,
+		Metadata: map[string]string{
+			"http_status": strconv.Itoa(e.HTTPStatus),
+			"sc_code":     strconv.Itoa(e.Code),
+		},
+	}
+	for i, d := range e.Details {
+		info.Metadata[fmt.Sprintf("sc_detail_%d", i)] = string(d)
+	}
+	details := []protoadapt.MessageV1{info}
+	if e.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// newAPIError builds an APIError from a failed response, body may or may not be the SC envelope
+func newAPIError(res *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		HTTPStatus: res.StatusCode,
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+	}
+	if err := json.Unmarshal(body, &apiErr.ScErrorResponse); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
+
+// transportError converts a failure to get any response from SC into a status error
+func transportError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrCircuitOpen
+	case errors.Is(err, errRateLimited):
+		return status.Error(codes.ResourceExhausted, errRateLimited.Error())
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "SafetyCulture API did not respond before the deadline")
+	case errors.Is(ctx.Err(), context.Canceled):
+		return status.Error(codes.Canceled, "request to SafetyCulture API was canceled")
+	default:
+		return status.Errorf(codes.Unavailable, "failed to reach SafetyCulture API: %v", err)
+	}
+}
+
+func httpStatusToCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499: // client closed request
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if httpStatus >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header, seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
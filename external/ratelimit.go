@@ -0,0 +1,81 @@
+package external
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Defaults for the SafetyCulture rate limiter, override with SetLimit and SetBurst
+const (
+	DefaultRateLimit = 10 // requests per second
+	DefaultRateBurst = 10
+)
+
+// errRateLimited is returned when a request can't get a token before its context deadline
+var errRateLimited = errors.New("SafetyCulture rate limit would be exceeded before the request deadline")
+
+// RateLimiter is a token bucket shared by every request the server makes to SafetyCulture,
+// so bulk operations from many clients can't push us over SC's own rate limits.
+type RateLimiter struct {
+	limiter *rate.Limiter
+	waiting atomic.Int64
+}
+
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+	}
+}
+
+func (l *RateLimiter) SetLimit(requestsPerSecond float64) {
+	l.limiter.SetLimit(rate.Limit(requestsPerSecond))
+}
+
+func (l *RateLimiter) SetBurst(burst int) {
+	l.limiter.SetBurst(burst)
+}
+
+// RateLimiterState is a snapshot of the limiter for diagnostics
+type RateLimiterState struct {
+	Limit   float64 `json:"limit"`   // requests per second
+	Burst   int     `json:"burst"`   // bucket size
+	Tokens  float64 `json:"tokens"`  // tokens available right now, negative when requests are queued
+	Waiting int64   `json:"waiting"` // requests currently queued for a token
+}
+
+func (l *RateLimiter) State() RateLimiterState {
+	return RateLimiterState{
+		Limit:   float64(l.limiter.Limit()),
+		Burst:   l.limiter.Burst(),
+		Tokens:  l.limiter.TokensAt(time.Now()),
+		Waiting: l.waiting.Load(),
+	}
+}
+
+// RateLimitTransport queues each request until the limiter has a token for it.
+// Requests that can't get one before their context deadline fail straight away with errRateLimited.
+type RateLimitTransport struct {
+	Base    http.RoundTripper
+	Limiter *RateLimiter
+}
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	t.Limiter.waiting.Add(1)
+	err := t.Limiter.limiter.Wait(ctx)
+	t.Limiter.waiting.Add(-1)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Wait fails early when the deadline is too close to get a token
+		return nil, errRateLimited
+	}
+	return t.Base.RoundTrip(req)
+}
@@ -0,0 +1,120 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryTransport retries SafetyCulture requests that failed for transient reasons:
+// connection errors, 429 and 5xx responses.
+//
+// Delays grow exponentially with full jitter, a Retry-After header from SC takes precedence,
+// and no retry is attempted if it can't finish before the request context's deadline
+// (which for RPC handlers is the incoming gRPC deadline).
+// Non-idempotent requests, e.g. creating an action, are only retried when they carry an Idempotency-Key.
+type RetryTransport struct {
+	Base        http.RoundTripper
+	MaxAttempts int           // total attempts including the first one
+	BaseDelay   time.Duration // delay before the first retry, doubled for every retry after
+	MaxDelay    time.Duration // cap on a single backoff delay
+}
+
+func NewRetryTransport(base http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Base:        base,
+		MaxAttempts: 4,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	}
+}
+
+// idempotentKey marks requests that are safe to repeat even though they are POSTs
+type idempotentKey struct{}
+
+func withIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	// the body can only be replayed if the request knows how to rebuild it
+	canRetry := isIdempotent(req) && (req.Body == nil || req.GetBody != nil)
+
+	for attempt := 1; ; attempt++ {
+		res, err := t.Base.RoundTrip(req)
+		if !canRetry || attempt >= t.MaxAttempts || !shouldRetry(ctx, res, err) {
+			return res, err
+		}
+
+		delay := t.backoff(attempt)
+		if res != nil {
+			if retryAfter := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); retryAfter > 0 {
+				delay = retryAfter
+			}
+		}
+
+		// don't start a wait we know will outlive the caller
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return res, err
+		}
+
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// backoff returns a random delay in [0, min(MaxDelay, BaseDelay*2^(attempt-1))]
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	delay := t.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > t.MaxDelay {
+		delay = t.MaxDelay
+	}
+	return rand.N(delay + 1)
+}
+
+func shouldRetry(ctx context.Context, res *http.Response, err error) bool {
+	// the caller gave up, retrying won't help
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		// waiting longer for our own limiter won't make the deadline any further away
+		return !errors.Is(err, errRateLimited)
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+}
@@ -1,4 +1,357 @@
-package main
+// Package external wraps the SafetyCulture actions API (tasks/v1/actions)
+// that the todo server mirrors its todos into.
+package external
 
-func main() {
-}
\ No newline at end of file
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultBaseURL is the public SafetyCulture API
+const DefaultBaseURL = "https://api.safetyculture.io"
+
+// Default SafetyCulture action statuses, used to map a todo's completed flag
+const (
+	StatusToDo     = "17e793a1-26a3-4ecd-99ca-f38ecc6eaa2e"
+	StatusComplete = "7223d809-553e-4714-a038-62dc98f3fbf3"
+)
+
+// ActionsAPI is the set of SafetyCulture action operations the server relies on.
+// SCClient implements it against the real API, tests can swap in a fake.
+type ActionsAPI interface {
+	CreateAction(ctx context.Context, req *CreateActionRequest) (*CreateActionResponse, error)
+	GetAction(ctx context.Context, id string) (*Action, error)
+	DeleteActions(ctx context.Context, ids []string) error
+	ListActions(ctx context.Context, req *ListActionsRequest) (*ListActionsResponse, error)
+	UpdateAction(ctx context.Context, id string, req *UpdateActionRequest) error
+}
+
+type CreateActionRequest struct {
+	TaskID        string         `json:"task_id"`
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	Collaborators []Collaborator `json:"collaborators,omitempty"`
+	// IdempotencyKey lets a failed create be retried without risking a duplicate action
+	IdempotencyKey string `json:"-"`
+}
+
+type CreateActionResponse struct {
+	ActionID string `json:"action_id"`
+}
+
+type ActionStatus struct {
+	StatusID string `json:"status_id"`
+	Label    string `json:"label,omitempty"`
+}
+
+// Collaborator types and roles, todos only use user assignees
+const (
+	CollaboratorUser = "USER"
+	RoleAssignee     = "ASSIGNEE"
+)
+
+// Collaborator is a user or group on an action
+type Collaborator struct {
+	CollaboratorID   string `json:"collaborator_id"`
+	CollaboratorType string `json:"collaborator_type"`
+	AssignedRole     string `json:"assigned_role"`
+}
+
+// Assignees makes each SC user id an assignee
+func Assignees(userIDs []string) []Collaborator {
+	collaborators := make([]Collaborator, 0, len(userIDs))
+	for _, id := range userIDs {
+		collaborators = append(collaborators, Collaborator{CollaboratorID: id, CollaboratorType: CollaboratorUser, AssignedRole: RoleAssignee})
+	}
+	return collaborators
+}
+
+// Action is the task inside a SafetyCulture action
+type Action struct {
+	TaskID        string         `json:"task_id"`
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	Status        ActionStatus   `json:"status"`
+	Collaborators []Collaborator `json:"collaborators,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	ModifiedAt    time.Time      `json:"modified_at"`
+}
+
+// Completed reports whether the action is in the complete status
+func (a *Action) Completed() bool {
+	return a.Status.StatusID == StatusComplete
+}
+
+type ListActionsRequest struct {
+	Offset   int `json:"offset"`
+	PageSize int `json:"page_size"`
+}
+
+type ListActionsResponse struct {
+	Actions []*Action
+	Total   int
+}
+
+// UpdateActionRequest only changes the fields that are set
+type UpdateActionRequest struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	StatusID    *string `json:"status_id,omitempty"`
+	// Assignees replaces every assignee, an empty slice removes them all
+	Assignees *[]Collaborator `json:"assignees,omitempty"`
+}
+
+// SCClient talks to the SafetyCulture API with a single bearer token
+type SCClient struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+	// RateLimiter is shared by every request made through HTTPClient, including retries
+	RateLimiter *RateLimiter
+	// Breaker fails requests fast while SC is down
+	Breaker *CircuitBreaker
+}
+
+func NewSCClient(baseURL, token string) *SCClient {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	limiter := NewRateLimiter(DefaultRateLimit, DefaultRateBurst)
+	breaker := NewCircuitBreaker(DefaultBreakerFailures, DefaultBreakerCoolDown)
+
+	// the breaker sees a request once its retries are exhausted,
+	// and every retry attempt has to wait for its own token
+	transport := &BreakerTransport{
+		Base: NewRetryTransport(&RateLimitTransport{
+			Base:    http.DefaultTransport,
+			Limiter: limiter,
+		}),
+		Breaker: breaker,
+	}
+
+	return &SCClient{
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		Token:       token,
+		HTTPClient:  &http.Client{Transport: transport},
+		RateLimiter: limiter,
+		Breaker:     breaker,
+	}
+}
+
+// response envelopes, SC nests the task inside an action object
+type actionEnvelope struct {
+	Task *Action `json:"task"`
+}
+
+type getActionResponse struct {
+	Action actionEnvelope `json:"action"`
+}
+
+type listActionsResponse struct {
+	Actions []actionEnvelope `json:"actions"`
+	Total   int              `json:"total"`
+}
+
+type deleteActionsPayload struct {
+	IDs []string `json:"ids"`
+}
+
+type updateTitlePayload struct {
+	Title string `json:"title"`
+}
+
+type updateDescriptionPayload struct {
+	Description string `json:"description"`
+}
+
+type updateStatusPayload struct {
+	StatusID string `json:"status_id"`
+}
+
+type updateAssigneesPayload struct {
+	Assignees []Collaborator `json:"assignees"`
+}
+
+func (c *SCClient) CreateAction(ctx context.Context, req *CreateActionRequest) (*CreateActionResponse, error) {
+	httpReq, err := c.newRequest(ctx, http.MethodPost, "/tasks/v1/actions", req)
+	if err != nil {
+		return nil, err
+	}
+	if req.IdempotencyKey != "" {
+		httpReq.Header.Add("Idempotency-Key", req.IdempotencyKey)
+	}
+
+	res := &CreateActionResponse{}
+	if err := c.send(httpReq, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *SCClient) GetAction(ctx context.Context, id string) (*Action, error) {
+	res := &getActionResponse{}
+	if err := c.do(ctx, http.MethodGet, "/tasks/v1/actions/"+id, nil, res); err != nil {
+		return nil, err
+	}
+	if res.Action.Task == nil {
+		return nil, fmt.Errorf("SafetyCulture API returned no task for action %s", id)
+	}
+	return res.Action.Task, nil
+}
+
+// Deleting the same ids twice has the same result, so the POST is safe to retry
+func (c *SCClient) DeleteActions(ctx context.Context, ids []string) error {
+	return c.do(withIdempotent(ctx), http.MethodPost, "/tasks/v1/actions/delete", deleteActionsPayload{IDs: ids}, nil)
+}
+
+func (c *SCClient) ListActions(ctx context.Context, req *ListActionsRequest) (*ListActionsResponse, error) {
+	// list is a read that happens to be a POST
+	res := &listActionsResponse{}
+	if err := c.do(withIdempotent(ctx), http.MethodPost, "/tasks/v1/actions/list", req, res); err != nil {
+		return nil, err
+	}
+
+	out := &ListActionsResponse{Total: res.Total}
+	for _, a := range res.Actions {
+		if a.Task != nil {
+			out.Actions = append(out.Actions, a.Task)
+		}
+	}
+	return out, nil
+}
+
+// UpdateAction sends one request per changed field, SC exposes a separate endpoint for each
+func (c *SCClient) UpdateAction(ctx context.Context, id string, req *UpdateActionRequest) error {
+	path := "/tasks/v1/actions/" + id
+	if req.Title != nil {
+		if err := c.do(ctx, http.MethodPut, path+"/title", updateTitlePayload{Title: *req.Title}, nil); err != nil {
+			return err
+		}
+	}
+	if req.Description != nil {
+		if err := c.do(ctx, http.MethodPut, path+"/description", updateDescriptionPayload{Description: *req.Description}, nil); err != nil {
+			return err
+		}
+	}
+	if req.StatusID != nil {
+		if err := c.do(ctx, http.MethodPut, path+"/status", updateStatusPayload{StatusID: *req.StatusID}, nil); err != nil {
+			return err
+		}
+	}
+	if req.Assignees != nil {
+		// SC rejects a null list, an empty one unassigns everyone
+		assignees := *req.Assignees
+		if assignees == nil {
+			assignees = []Collaborator{}
+		}
+		if err := c.do(ctx, http.MethodPut, path+"/assignees", updateAssigneesPayload{Assignees: assignees}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends a JSON request to the SC API and decodes the response into out, if out is not nil
+func (c *SCClient) do(ctx context.Context, method, path string, payload any, out any) error {
+	httpReq, err := c.newRequest(ctx, method, path, payload)
+	if err != nil {
+		return err
+	}
+	return c.send(httpReq, out)
+}
+
+func (c *SCClient) newRequest(ctx context.Context, method, path string, payload any) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode payload: %w", err)
+		}
+		body = bytes.NewReader(payloadBytes)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Add("accept", "application/json")
+	if payload != nil {
+		httpReq.Header.Add("content-type", "application/json")
+	}
+	httpReq.Header.Add("authorization", "Bearer "+c.token(ctx))
+	return httpReq, nil
+}
+
+type tokenKey struct{}
+
+// WithToken makes the calls made with ctx authenticate as token instead of the client's own Token,
+// so actions are attributed to the caller's SC account. An empty token goes back to the client's own.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+func (c *SCClient) token(ctx context.Context) string {
+	if token, _ := ctx.Value(tokenKey{}).(string); token != "" {
+		return token
+	}
+	return c.Token
+}
+
+func (c *SCClient) send(httpReq *http.Request, out any) error {
+	res, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return transportError(httpReq.Context(), err)
+	}
+
+	resBody, err := handleResponse(res)
+	if err != nil {
+		return err
+	}
+
+	if out == nil || len(resBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resBody, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// handleResponse reads the body and turns SC error responses into an *APIError
+func handleResponse(res *http.Response) ([]byte, error) {
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to read response from SafetyCulture API: %v", err)
+	}
+
+	if res.StatusCode >= 400 {
+		return nil, newAPIError(res, body)
+	}
+
+	// an empty body means success, e.g. for bulk delete
+	if len(body) == 0 {
+		return body, nil
+	}
+
+	// some endpoints report errors with a 200, so also check for the envelope's "code" field
+	var result map[string]any
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decode response from SafetyCulture API: %v", err)
+	}
+	if _, exists := result["code"]; exists {
+		return nil, newAPIError(res, body)
+	}
+
+	return body, nil
+}
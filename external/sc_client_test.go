@@ -0,0 +1,266 @@
+package external_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/external/scfake"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const token = "test-token"
+
+func newClient(t *testing.T) (*external.SCClient, *scfake.Server) {
+	fake := scfake.New(token)
+	t.Cleanup(fake.Close)
+	return external.NewSCClient(fake.URL, token), fake
+}
+
+func retryTransport(c *external.SCClient) *external.RetryTransport {
+	return c.HTTPClient.Transport.(*external.BreakerTransport).Base.(*external.RetryTransport)
+}
+
+func createAction(t *testing.T, c *external.SCClient, id string) {
+	t.Helper()
+	_, err := c.CreateAction(context.Background(), &external.CreateActionRequest{TaskID: id, Title: "title " + id, IdempotencyKey: "create-" + id})
+	if err != nil {
+		t.Fatalf("CreateAction: %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		fault        scfake.Fault
+		call         func(c *external.SCClient) error
+		wantCode     codes.Code
+		wantRequests int
+	}{
+		{
+			name:  "transient failures are retried",
+			fault: scfake.Fault{Method: http.MethodGet, Status: http.StatusServiceUnavailable, Code: codes.Unavailable, Times: 2},
+			call: func(c *external.SCClient) error {
+				_, err := c.GetAction(context.Background(), "a")
+				return err
+			},
+			wantCode:     codes.OK,
+			wantRequests: 3,
+		},
+		{
+			name:  "client errors aren't retried",
+			fault: scfake.Fault{Method: http.MethodGet, Status: http.StatusForbidden, Code: codes.PermissionDenied, Times: 1},
+			call: func(c *external.SCClient) error {
+				_, err := c.GetAction(context.Background(), "a")
+				return err
+			},
+			wantCode:     codes.PermissionDenied,
+			wantRequests: 1,
+		},
+		{
+			name:  "creates with an idempotency key are retried",
+			fault: scfake.Fault{Method: http.MethodPost, Status: http.StatusBadGateway, Code: codes.Unavailable, Times: 1},
+			call: func(c *external.SCClient) error {
+				_, err := c.CreateAction(context.Background(), &external.CreateActionRequest{TaskID: "b", Title: "b", IdempotencyKey: "create-b"})
+				return err
+			},
+			wantCode:     codes.OK,
+			wantRequests: 2,
+		},
+		{
+			name:  "creates without one aren't",
+			fault: scfake.Fault{Method: http.MethodPost, Status: http.StatusBadGateway, Code: codes.Unavailable, Times: 1},
+			call: func(c *external.SCClient) error {
+				_, err := c.CreateAction(context.Background(), &external.CreateActionRequest{TaskID: "b", Title: "b"})
+				return err
+			},
+			wantCode:     codes.Unavailable,
+			wantRequests: 1,
+		},
+		{
+			name:  "retries give up after MaxAttempts",
+			fault: scfake.Fault{Method: http.MethodGet, Status: http.StatusInternalServerError, Code: codes.Internal},
+			call: func(c *external.SCClient) error {
+				_, err := c.GetAction(context.Background(), "a")
+				return err
+			},
+			wantCode:     codes.Internal,
+			wantRequests: 4,
+		},
+		{
+			name:  "a Retry-After past the deadline isn't waited for",
+			fault: scfake.Fault{Method: http.MethodGet, Status: http.StatusTooManyRequests, Code: codes.ResourceExhausted, RetryAfter: 5 * time.Second, Times: 1},
+			call: func(c *external.SCClient) error {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				_, err := c.GetAction(ctx, "a")
+				return err
+			},
+			wantCode:     codes.ResourceExhausted,
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newClient(t)
+			createAction(t, c, "a")
+			retryTransport(c).BaseDelay = time.Millisecond
+			before := fake.Requests()
+
+			fake.InjectFault(tt.fault)
+			err := tt.call(c)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("got %v, want code %v", err, tt.wantCode)
+			}
+			if got := fake.Requests() - before; got != tt.wantRequests {
+				t.Fatalf("SC got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	c, fake := newClient(t)
+	createAction(t, c, "a")
+	retryTransport(c).MaxAttempts = 1
+	c.Breaker.FailureThreshold = 2
+	c.Breaker.CoolDown = 50 * time.Millisecond
+	ctx := context.Background()
+
+	fake.InjectFault(scfake.Fault{Status: http.StatusServiceUnavailable, Code: codes.Unavailable})
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetAction(ctx, "a"); status.Code(err) != codes.Unavailable {
+			t.Fatalf("call %d: got %v, want Unavailable from SC", i, err)
+		}
+	}
+	if state := c.Breaker.State().State; state != external.BreakerOpen {
+		t.Fatalf("breaker is %s after %d failures, want open", state, c.Breaker.FailureThreshold)
+	}
+
+	before := fake.Requests()
+	if _, err := c.GetAction(ctx, "a"); !errors.Is(err, external.ErrCircuitOpen) {
+		t.Fatalf("got %v while open, want ErrCircuitOpen", err)
+	}
+	if fake.Requests() != before {
+		t.Fatal("an open breaker still called SC")
+	}
+
+	// the probe fails, so the breaker opens again for another cool-down
+	time.Sleep(c.Breaker.CoolDown)
+	if _, err := c.GetAction(ctx, "a"); status.Code(err) != codes.Unavailable {
+		t.Fatalf("probe: got %v, want Unavailable from SC", err)
+	}
+	if _, err := c.GetAction(ctx, "a"); !errors.Is(err, external.ErrCircuitOpen) {
+		t.Fatalf("got %v after a failed probe, want ErrCircuitOpen", err)
+	}
+
+	// SC is back, the next probe closes it
+	fake.ClearFaults()
+	time.Sleep(c.Breaker.CoolDown)
+	if _, err := c.GetAction(ctx, "a"); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if state := c.Breaker.State(); state.State != external.BreakerClosed || state.Failures != 0 {
+		t.Fatalf("breaker is %+v after a successful probe, want closed", state)
+	}
+}
+
+func TestBreakerOpensWhenSCHangs(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(c *external.SCClient)
+		ctx   func() (context.Context, context.CancelFunc)
+	}{
+		{
+			name: "calls run out of RPC deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+		},
+		{
+			name:  "calls hit the client timeout",
+			setup: func(c *external.SCClient) { c.HTTPClient.Timeout = 20 * time.Millisecond },
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newClient(t)
+			createAction(t, c, "a")
+			retryTransport(c).MaxAttempts = 1
+			c.Breaker.FailureThreshold = 2
+			if tt.setup != nil {
+				tt.setup(c)
+			}
+			fake.SetLatency(time.Second)
+
+			for i := 0; i < 2; i++ {
+				ctx, cancel := tt.ctx()
+				_, err := c.GetAction(ctx, "a")
+				cancel()
+				if err == nil {
+					t.Fatalf("call %d to a hanging SC succeeded", i)
+				}
+			}
+			if state := c.Breaker.State().State; state != external.BreakerOpen {
+				t.Fatalf("breaker is %s after %d timed out calls, want open", state, c.Breaker.FailureThreshold)
+			}
+			if _, err := c.GetAction(context.Background(), "a"); !errors.Is(err, external.ErrCircuitOpen) {
+				t.Fatalf("got %v, want ErrCircuitOpen instead of waiting on SC again", err)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresCanceledCalls(t *testing.T) {
+	c, fake := newClient(t)
+	createAction(t, c, "a")
+	c.Breaker.FailureThreshold = 1
+	fake.SetLatency(time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := c.GetAction(ctx, "a"); status.Code(err) != codes.Canceled {
+		t.Fatalf("got %v, want Canceled", err)
+	}
+	if state := c.Breaker.State(); state.State != external.BreakerClosed || state.Failures != 0 {
+		t.Fatalf("breaker is %+v after the caller hung up, want it untouched", state)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	c, fake := newClient(t)
+	c.RateLimiter.SetLimit(1)
+	c.RateLimiter.SetBurst(1)
+	createAction(t, c, "a")
+	// createAction used the only token, the next one is a second away
+	before := fake.Requests()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetAction(ctx, "a")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("got %v, want ResourceExhausted from the limiter", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("limiter took %s to turn down a request it could never serve in time", elapsed)
+	}
+	if fake.Requests() != before {
+		t.Fatal("a rate limited request still reached SC")
+	}
+	if state := c.Breaker.State(); state.Failures != 0 {
+		t.Fatalf("rate limiting counted as %d SC failures", state.Failures)
+	}
+
+	// without a deadline the request waits its turn
+	if _, err := c.GetAction(context.Background(), "a"); err != nil {
+		t.Fatalf("GetAction after waiting for a token: %v", err)
+	}
+}
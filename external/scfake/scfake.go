@@ -0,0 +1,408 @@
+// Package scfake is an in-memory fake of the SafetyCulture actions API (tasks/v1/actions).
+//
+// It serves the same endpoints and envelopes as the real API over httptest, so the todo
+// server can be run and exercised end-to-end without network access or a real API token:
+//
+//	fake := scfake.New("test-token")
+//	defer fake.Close()
+//	sc := external.NewSCClient(fake.URL, "test-token")
+//
+// Faults and latency can be injected to exercise retries, rate limiting and the circuit breaker.
+// With SetWebhook the fake also sends signed action events, like SC does for a registered webhook.
+package scfake
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/webhook"
+	"google.golang.org/grpc/codes"
+)
+
+// Fault makes matching requests fail instead of reaching the fake's state
+type Fault struct {
+	Method     string // empty matches any method
+	PathPrefix string // empty matches any path
+	Status     int    // HTTP status to respond with
+	Code       codes.Code
+	Message    string
+	RetryAfter time.Duration // sent as a Retry-After header when set
+	Times      int           // number of requests to fail, 0 fails every matching request
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.PathPrefix)
+}
+
+// Server is a running fake, URL is the base URL to hand to external.NewSCClient
+type Server struct {
+	URL string
+
+	token  string
+	server *httptest.Server
+
+	mu       sync.Mutex
+	actions  map[string]*external.Action
+	faults   []*Fault
+	latency  time.Duration
+	requests int
+
+	webhookURL    string
+	webhookSecret []byte
+}
+
+// New starts a fake that expects token as its bearer token, an empty token accepts any caller
+func New(token string) *Server {
+	s := &Server{
+		token:   token,
+		actions: make(map[string]*external.Action),
+	}
+	s.server = httptest.NewServer(s.Handler())
+	s.URL = s.server.URL
+	return s
+}
+
+// NewUnstarted builds a fake without a listener, serve its Handler yourself
+func NewUnstarted(token string) *Server {
+	return &Server{
+		token:   token,
+		actions: make(map[string]*external.Action),
+	}
+}
+
+func (s *Server) Close() {
+	if s.server != nil {
+		s.server.Close()
+	}
+}
+
+// Handler routes the tasks/v1/actions endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tasks/v1/actions", s.createAction)
+	mux.HandleFunc("GET /tasks/v1/actions/{id}", s.getAction)
+	mux.HandleFunc("POST /tasks/v1/actions/delete", s.deleteActions)
+	mux.HandleFunc("POST /tasks/v1/actions/list", s.listActions)
+	mux.HandleFunc("PUT /tasks/v1/actions/{id}/{field}", s.updateAction)
+	return s.middleware(mux)
+}
+
+// SetWebhook makes the fake post a signed event to url after every action change, an empty url turns it off
+func (s *Server) SetWebhook(url, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhookURL = url
+	s.webhookSecret = []byte(secret)
+}
+
+// InjectFault adds a fault, faults are checked in the order they were added
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetLatency delays every response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Requests is the number of requests received so far, including rejected ones
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Action returns a copy of the stored action, as if it was edited in the SC web app
+func (s *Server) Action(id string) (*external.Action, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.actions[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *a
+	return &copied, true
+}
+
+// PutAction creates or replaces an action directly, bypassing the API
+func (s *Server) PutAction(a *external.Action) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *a
+	s.actions[a.TaskID] = &copied
+}
+
+// RemoveAction deletes an action directly, bypassing the API
+func (s *Server) RemoveAction(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.actions, id)
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		latency := s.latency
+		fault := s.takeFault(r)
+		s.mu.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if s.token != "" && r.Header.Get("authorization") != "Bearer "+s.token {
+			writeError(w, http.StatusUnauthorized, codes.Unauthenticated, "invalid bearer token")
+			return
+		}
+
+		if fault != nil {
+			if fault.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(fault.RetryAfter.Round(time.Second)/time.Second)))
+			}
+			writeError(w, fault.Status, fault.Code, fault.Message)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// takeFault finds the first fault matching r and uses up one of its Times, s.mu must be held
+func (s *Server) takeFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if !f.matches(r) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+type createActionPayload struct {
+	TaskID        string                  `json:"task_id"`
+	Title         string                  `json:"title"`
+	Description   string                  `json:"description"`
+	Collaborators []external.Collaborator `json:"collaborators"`
+}
+
+func (s *Server) createAction(w http.ResponseWriter, r *http.Request) {
+	var payload createActionPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, codes.InvalidArgument, "invalid JSON body")
+		return
+	}
+	if payload.TaskID == "" {
+		writeError(w, http.StatusBadRequest, codes.InvalidArgument, "task_id is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.actions[payload.TaskID]; exists {
+		writeError(w, http.StatusConflict, codes.AlreadyExists, "action "+payload.TaskID+" already exists")
+		return
+	}
+
+	now := time.Now().UTC()
+	s.actions[payload.TaskID] = &external.Action{
+		TaskID:        payload.TaskID,
+		Title:         payload.Title,
+		Description:   payload.Description,
+		Status:        external.ActionStatus{StatusID: external.StatusToDo, Label: "To do"},
+		Collaborators: payload.Collaborators,
+		CreatedAt:     now,
+		ModifiedAt:    now,
+	}
+	s.notify(webhook.EventActionCreated, payload.TaskID)
+	writeJSON(w, map[string]string{"action_id": payload.TaskID})
+}
+
+func (s *Server) getAction(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.actions[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, codes.NotFound, "action "+id+" not found")
+		return
+	}
+	writeJSON(w, map[string]any{"action": map[string]any{"task": a}})
+}
+
+func (s *Server) deleteActions(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, codes.InvalidArgument, "invalid JSON body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range payload.IDs {
+		if _, exists := s.actions[id]; exists {
+			delete(s.actions, id)
+			s.notify(webhook.EventActionDeleted, id)
+		}
+	}
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) listActions(w http.ResponseWriter, r *http.Request) {
+	var payload external.ListActionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, codes.InvalidArgument, "invalid JSON body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := make([]*external.Action, 0, len(s.actions))
+	for _, a := range s.actions {
+		all = append(all, a)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].CreatedAt.Before(all[j].CreatedAt)
+		}
+		return all[i].TaskID < all[j].TaskID
+	})
+
+	start := min(max(payload.Offset, 0), len(all))
+	end := len(all)
+	if payload.PageSize > 0 {
+		end = min(start+payload.PageSize, len(all))
+	}
+
+	actions := []map[string]any{}
+	for _, a := range all[start:end] {
+		actions = append(actions, map[string]any{"task": a})
+	}
+	writeJSON(w, map[string]any{"actions": actions, "total": len(all)})
+}
+
+func (s *Server) updateAction(w http.ResponseWriter, r *http.Request) {
+	id, field := r.PathValue("id"), r.PathValue("field")
+
+	var payload struct {
+		Title       *string                  `json:"title"`
+		Description *string                  `json:"description"`
+		StatusID    *string                  `json:"status_id"`
+		Assignees   *[]external.Collaborator `json:"assignees"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, codes.InvalidArgument, "invalid JSON body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.actions[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, codes.NotFound, "action "+id+" not found")
+		return
+	}
+
+	switch {
+	case field == "title" && payload.Title != nil:
+		a.Title = *payload.Title
+	case field == "description" && payload.Description != nil:
+		a.Description = *payload.Description
+	case field == "status" && payload.StatusID != nil:
+		a.Status = external.ActionStatus{StatusID: *payload.StatusID}
+	case field == "assignees" && payload.Assignees != nil:
+		a.Collaborators = *payload.Assignees
+	default:
+		writeError(w, http.StatusBadRequest, codes.InvalidArgument, "unsupported update of "+field)
+		return
+	}
+	a.ModifiedAt = time.Now().UTC()
+	s.notify(webhook.EventActionUpdated, id)
+	writeJSON(w, map[string]any{})
+}
+
+// notify sends an action event to the webhook in the background, s.mu must be held
+func (s *Server) notify(eventType, id string) {
+	if s.webhookURL == "" {
+		return
+	}
+
+	var event webhook.Event
+	event.WebhookID = "scfake"
+	event.Event.DateTriggered = time.Now().UTC()
+	event.Event.EventTypes = []string{eventType}
+	event.Resource.ID = id
+	event.Resource.Type = "ACTION"
+	body, _ := json.Marshal(event)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := "sha256=" + hex.EncodeToString(webhook.Sign(s.webhookSecret, timestamp, body))
+	url := s.webhookURL
+
+	go func() {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Failed to build webhook request: %v", err)
+			return
+		}
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set(webhook.TimestampHeader, timestamp)
+		req.Header.Set(webhook.SignatureHeader, signature)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Printf("Failed to send webhook for action %s: %v", id, err)
+			return
+		}
+		res.Body.Close()
+	}()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds with SC's error envelope
+func writeError(w http.ResponseWriter, httpStatus int, code codes.Code, message string) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(external.ScErrorResponse{Code: int(code), Message: message})
+}
@@ -0,0 +1,237 @@
+// Package idempotency lets clients retry writes safely.
+//
+// A request carrying an idempotency key, either in its idempotency_key field or as
+// "idempotency-key" metadata, has its response stored for TTL. A retry with the same
+// key gets the stored response back without the handler running again, so a client
+// that timed out can't create a todo twice. Only successful responses are stored,
+// a failed request can be retried with the same key and will run again. When callers
+// are authenticated each of them has their own keys, and each tenant always has its own.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/auth"
+	"github.com/jerryhong21/todo-grpc/store"
+	"github.com/jerryhong21/todo-grpc/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// MetadataKey is the metadata header a key can be sent in instead of the request field
+const MetadataKey = "idempotency-key"
+
+// ReplayedHeader is set to "true" on responses answered from a stored record
+const ReplayedHeader = "idempotent-replayed"
+
+// DefaultTTL is how long a response is kept for replays
+const DefaultTTL = 24 * time.Hour
+
+// maxKeyLength keeps keys to a sensible size, a UUID is 36 characters
+const maxKeyLength = 255
+
+// purgeInterval is how often Run removes expired records
+const purgeInterval = time.Hour
+
+// keyed is implemented by the requests that accept an idempotency_key field.
+// Requests that don't implement it are never deduplicated, even if metadata carries a key.
+type keyed interface {
+	proto.Message
+	GetIdempotencyKey() string
+}
+
+type Interceptor struct {
+	TTL time.Duration
+
+	records store.IdempotencyStore
+
+	mu       sync.Mutex
+	inflight map[string]chan struct{} // requests being handled, so a concurrent retry waits instead of running twice
+}
+
+func NewInterceptor(records store.IdempotencyStore) *Interceptor {
+	return &Interceptor{
+		TTL:      DefaultTTL,
+		records:  records,
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Unary is a grpc.UnaryServerInterceptor
+func (i *Interceptor) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	msg, ok := req.(keyed)
+	if !ok {
+		return handler(ctx, req)
+	}
+	key, err := requestKey(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return handler(ctx, req)
+	}
+	// keys belong to the caller, so nobody can replay someone else's response by guessing their key
+	if id, ok := auth.FromContext(ctx); ok {
+		key = id.Method + ":" + id.Subject + "\x00" + key
+	}
+	if t := tenant.FromContext(ctx); t != tenant.Default {
+		key = "@" + t + "\x00" + key
+	}
+
+	hash, err := fingerprint(msg)
+	if err != nil {
+		log.Printf("Failed to fingerprint %s request: %v", info.FullMethod, err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	release, err := i.acquire(ctx, info.FullMethod+"\x00"+key)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rec, err := i.records.GetIdempotency(ctx, info.FullMethod, key)
+	switch {
+	case err == nil && time.Now().Before(rec.ExpiresAt):
+		if !bytes.Equal(rec.RequestHash, hash) {
+			return nil, status.Error(codes.InvalidArgument, "idempotency key was already used for a different request")
+		}
+		return replay(ctx, rec)
+	case err != nil && !errors.Is(err, store.ErrNotFound):
+		log.Printf("Failed to read idempotency key: %v", err)
+		return nil, status.Error(codes.Internal, "failed to read idempotency key")
+	}
+
+	res, err := handler(ctx, req)
+	if err != nil {
+		return res, err
+	}
+
+	resMsg, ok := res.(proto.Message)
+	if !ok {
+		return res, nil
+	}
+	encoded, err := proto.Marshal(resMsg)
+	if err != nil {
+		log.Printf("Failed to encode %s response for idempotency key: %v", info.FullMethod, err)
+		return res, nil
+	}
+	now := time.Now()
+	// stored even if the caller's deadline passed while the handler ran, the change is made and a retry must see that
+	err = i.records.PutIdempotency(context.WithoutCancel(ctx), &store.IdempotencyRecord{
+		Method:       info.FullMethod,
+		Key:          key,
+		RequestHash:  hash,
+		ResponseType: string(resMsg.ProtoReflect().Descriptor().FullName()),
+		Response:     encoded,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(i.TTL),
+	})
+	if err != nil {
+		// the request did succeed, so still answer it, a retry will just run again
+		log.Printf("Failed to store idempotency key for %s: %v", info.FullMethod, err)
+	}
+	return res, nil
+}
+
+// Run removes expired records every hour until ctx is done
+func (i *Interceptor) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := i.records.DeleteExpiredIdempotency(ctx, time.Now()); err != nil {
+			log.Printf("Failed to delete expired idempotency keys: %v", err)
+		}
+	}
+}
+
+// acquire waits until no other request with the same key is being handled
+func (i *Interceptor) acquire(ctx context.Context, id string) (func(), error) {
+	for {
+		i.mu.Lock()
+		wait, busy := i.inflight[id]
+		if !busy {
+			done := make(chan struct{})
+			i.inflight[id] = done
+			i.mu.Unlock()
+			return func() {
+				i.mu.Lock()
+				delete(i.inflight, id)
+				i.mu.Unlock()
+				close(done)
+			}, nil
+		}
+		i.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// requestKey returns the key from the request field or metadata, they must agree if both are set
+func requestKey(ctx context.Context, msg keyed) (string, error) {
+	key := msg.GetIdempotencyKey()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(MetadataKey); len(values) > 0 && values[0] != "" {
+			if key != "" && key != values[0] {
+				return "", status.Error(codes.InvalidArgument, "idempotency key in metadata doesn't match idempotency_key")
+			}
+			key = values[0]
+		}
+	}
+	if len(key) > maxKeyLength {
+		return "", status.Errorf(codes.InvalidArgument, "idempotency key is longer than %d characters", maxKeyLength)
+	}
+	return key, nil
+}
+
+// fingerprint hashes the request without its key, so the same request sent with the key
+// in metadata or in the field is recognised as a retry
+func fingerprint(msg keyed) ([]byte, error) {
+	clone := proto.Clone(msg)
+	if fd := clone.ProtoReflect().Descriptor().Fields().ByName("idempotency_key"); fd != nil {
+		clone.ProtoReflect().Clear(fd)
+	}
+	encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(clone)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(encoded)
+	return sum[:], nil
+}
+
+func replay(ctx context.Context, rec *store.IdempotencyRecord) (any, error) {
+	msgType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(rec.ResponseType))
+	if err != nil {
+		log.Printf("Failed to find stored response type %s: %v", rec.ResponseType, err)
+		return nil, status.Error(codes.Internal, "failed to replay stored response")
+	}
+	res := msgType.New().Interface()
+	if err := proto.Unmarshal(rec.Response, res); err != nil {
+		log.Printf("Failed to decode stored response: %v", err)
+		return nil, status.Error(codes.Internal, "failed to replay stored response")
+	}
+	// best effort, the response is the same either way
+	_ = grpc.SetHeader(ctx, metadata.Pairs(ReplayedHeader, "true"))
+	return res, nil
+}
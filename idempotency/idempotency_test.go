@@ -0,0 +1,296 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/auth"
+	"github.com/jerryhong21/todo-grpc/idempotency"
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/store"
+	"github.com/jerryhong21/todo-grpc/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var createInfo = &grpc.UnaryServerInfo{FullMethod: "/todo.TodoService/CreateTodo"}
+
+// counter is a handler that creates a todo with a new id on every call
+type counter struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (c *counter) handle(ctx context.Context, req any) (any, error) {
+	n := c.calls.Add(1)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &pb.Todo{Id: fmt.Sprint(n), Title: req.(*pb.CreateTodoRequest).GetTitle()}, nil
+}
+
+func call(t *testing.T, i *idempotency.Interceptor, ctx context.Context, req *pb.CreateTodoRequest, c *counter) (*pb.Todo, error) {
+	t.Helper()
+	res, err := i.Unary(ctx, req, createInfo, c.handle)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*pb.Todo), nil
+}
+
+func withMetadataKey(ctx context.Context, key string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(idempotency.MetadataKey, key))
+}
+
+func TestReplay(t *testing.T) {
+	i := idempotency.NewInterceptor(store.NewMemoryStore())
+	c := &counter{}
+	ctx := context.Background()
+
+	first, err := call(t, i, ctx, &pb.CreateTodoRequest{Title: "a", IdempotencyKey: "k"}, c)
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	// the same key in metadata instead of the field is the same request
+	again, err := call(t, i, withMetadataKey(ctx, "k"), &pb.CreateTodoRequest{Title: "a"}, c)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if again.GetId() != first.GetId() || c.calls.Load() != 1 {
+		t.Fatalf("retry got todo %s after %d handler calls, want %s replayed", again.GetId(), c.calls.Load(), first.GetId())
+	}
+
+	if _, err := call(t, i, ctx, &pb.CreateTodoRequest{Title: "a"}, c); err != nil || c.calls.Load() != 2 {
+		t.Fatalf("a request without a key wasn't handled: %v", err)
+	}
+}
+
+func TestRejectedKeys(t *testing.T) {
+	i := idempotency.NewInterceptor(store.NewMemoryStore())
+	c := &counter{}
+	ctx := context.Background()
+	if _, err := call(t, i, ctx, &pb.CreateTodoRequest{Title: "a", IdempotencyKey: "k"}, c); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  *pb.CreateTodoRequest
+	}{
+		{
+			name: "key reused for a different request",
+			ctx:  ctx,
+			req:  &pb.CreateTodoRequest{Title: "b", IdempotencyKey: "k"},
+		},
+		{
+			name: "metadata and field disagree",
+			ctx:  withMetadataKey(ctx, "other"),
+			req:  &pb.CreateTodoRequest{Title: "a", IdempotencyKey: "k"},
+		},
+		{
+			name: "key too long",
+			ctx:  ctx,
+			req:  &pb.CreateTodoRequest{Title: "a", IdempotencyKey: strings.Repeat("k", 256)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := c.calls.Load()
+			if _, err := call(t, i, tt.ctx, tt.req, c); status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("got %v, want INVALID_ARGUMENT", err)
+			}
+			if c.calls.Load() != before {
+				t.Fatal("the handler ran for a rejected key")
+			}
+		})
+	}
+}
+
+func TestKeysAreScoped(t *testing.T) {
+	as := func(subject, tenantID string) context.Context {
+		ctx := context.Background()
+		if subject != "" {
+			ctx = auth.NewContext(ctx, &auth.Identity{Subject: subject, Method: auth.MethodAPIKey})
+		}
+		return tenant.NewContext(ctx, tenantID)
+	}
+	tests := []struct {
+		name        string
+		first, then context.Context
+		wantReplay  bool
+	}{
+		{name: "same caller", first: as("alice", ""), then: as("alice", ""), wantReplay: true},
+		{name: "another caller", first: as("alice", ""), then: as("bob", "")},
+		{name: "anonymous then a caller", first: as("", ""), then: as("alice", "")},
+		{name: "same tenant", first: as("", "team-a"), then: as("", "team-a"), wantReplay: true},
+		{name: "another tenant", first: as("", "team-a"), then: as("", "team-b")},
+		{name: "same caller in another tenant", first: as("alice", "team-a"), then: as("alice", "team-b")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := idempotency.NewInterceptor(store.NewMemoryStore())
+			c := &counter{}
+			req := &pb.CreateTodoRequest{Title: "a", IdempotencyKey: "k"}
+			first, err := call(t, i, tt.first, req, c)
+			if err != nil {
+				t.Fatalf("first call: %v", err)
+			}
+			then, err := call(t, i, tt.then, req, c)
+			if err != nil {
+				t.Fatalf("second call: %v", err)
+			}
+			if replayed := then.GetId() == first.GetId(); replayed != tt.wantReplay {
+				t.Fatalf("second call replayed %v, want %v", replayed, tt.wantReplay)
+			}
+		})
+	}
+}
+
+func TestConcurrentRetriesWait(t *testing.T) {
+	i := idempotency.NewInterceptor(store.NewMemoryStore())
+	var calls atomic.Int32
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := func(ctx context.Context, req any) (any, error) {
+		if calls.Add(1) == 1 {
+			close(entered)
+		}
+		<-unblock
+		return &pb.Todo{Id: "only"}, nil
+	}
+	req := &pb.CreateTodoRequest{Title: "a", IdempotencyKey: "k"}
+
+	var wg sync.WaitGroup
+	results := make([]any, 5)
+	errs := make([]error, 5)
+	run := func(n int) {
+		defer wg.Done()
+		results[n], errs[n] = i.Unary(context.Background(), req, createInfo, handler)
+	}
+	wg.Add(1)
+	go run(0)
+	<-entered
+	// the retries arrive while the first call is still being handled
+	for n := 1; n < 5; n++ {
+		wg.Add(1)
+		go run(n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("the handler ran %d times for one key", calls.Load())
+	}
+	for n := range results {
+		if errs[n] != nil || results[n].(*pb.Todo).GetId() != "only" {
+			t.Fatalf("call %d got %v, %v, want the first call's todo", n, results[n], errs[n])
+		}
+	}
+
+	// a waiting retry gives up at its own deadline
+	blocked := make(chan struct{})
+	go i.Unary(context.Background(), &pb.CreateTodoRequest{Title: "b", IdempotencyKey: "slow"}, createInfo, func(ctx context.Context, req any) (any, error) {
+		<-blocked
+		return &pb.Todo{}, nil
+	})
+	defer close(blocked)
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := i.Unary(ctx, &pb.CreateTodoRequest{Title: "b", IdempotencyKey: "slow"}, createInfo, handler); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("waiting retry got %v, want DEADLINE_EXCEEDED", err)
+	}
+}
+
+func TestFailuresAreNotStored(t *testing.T) {
+	i := idempotency.NewInterceptor(store.NewMemoryStore())
+	c := &counter{err: status.Error(codes.Unavailable, "SC is down")}
+	req := &pb.CreateTodoRequest{Title: "a", IdempotencyKey: "k"}
+
+	if _, err := call(t, i, context.Background(), req, c); status.Code(err) != codes.Unavailable {
+		t.Fatalf("got %v, want the handler's error", err)
+	}
+	c.err = nil
+	if _, err := call(t, i, context.Background(), req, c); err != nil || c.calls.Load() != 2 {
+		t.Fatalf("retry after a failure got %v after %d calls, want it handled again", err, c.calls.Load())
+	}
+}
+
+func TestTTL(t *testing.T) {
+	i := idempotency.NewInterceptor(store.NewMemoryStore())
+	i.TTL = 10 * time.Millisecond
+	c := &counter{}
+	req := &pb.CreateTodoRequest{Title: "a", IdempotencyKey: "k"}
+
+	first, err := call(t, i, context.Background(), req, c)
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	again, err := call(t, i, context.Background(), req, c)
+	if err != nil {
+		t.Fatalf("call after the TTL: %v", err)
+	}
+	if again.GetId() == first.GetId() {
+		t.Fatal("an expired key was replayed")
+	}
+}
+
+func TestReplayAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todos.db")
+	req := &pb.CreateTodoRequest{Title: "a", IdempotencyKey: "k"}
+	c := &counter{}
+
+	records, err := store.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	first, err := call(t, idempotency.NewInterceptor(records), context.Background(), req, c)
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if err := records.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := store.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { reopened.Close() })
+	again, err := call(t, idempotency.NewInterceptor(reopened), context.Background(), req, c)
+	if err != nil {
+		t.Fatalf("retry after the restart: %v", err)
+	}
+	if again.GetId() != first.GetId() || c.calls.Load() != 1 {
+		t.Fatalf("retry after the restart got todo %s after %d handler calls, want %s replayed", again.GetId(), c.calls.Load(), first.GetId())
+	}
+}
+
+func TestUnkeyedRequestsPassThrough(t *testing.T) {
+	i := idempotency.NewInterceptor(store.NewMemoryStore())
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return nil, errors.New("handled")
+	}
+	// GetTodoRequest has no idempotency_key, so a key in metadata is ignored
+	ctx := withMetadataKey(context.Background(), "k")
+	for n := 0; n < 2; n++ {
+		i.Unary(ctx, &pb.GetTodoRequest{Id: "a"}, &grpc.UnaryServerInfo{FullMethod: "/todo.TodoService/GetTodo"}, handler)
+	}
+	if calls != 2 {
+		t.Fatalf("the handler ran %d times, want every call handled", calls)
+	}
+}
@@ -0,0 +1,577 @@
+// Package outbox applies queued SafetyCulture writes in the background.
+//
+// Handlers commit a change to the local store together with an outbox operation
+// describing the matching SC call, so a todo can be created, updated or deleted
+// while SC is unreachable. The Worker replays the operations in order, retrying
+// with backoff, and records the result on each todo's sync_state.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/external"
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Worker defaults
+const (
+	DefaultMaxAttempts  = 10
+	DefaultPollInterval = 5 * time.Second
+	DefaultBaseDelay    = time.Second
+	DefaultMaxDelay     = 5 * time.Minute
+	// sentRetention is how long ChangedSince remembers an operation was sent, far longer than listing SC takes
+	sentRetention = time.Hour
+	// DefaultApplyTimeout bounds a single SC call, so a hung SC can't hold up the outbox forever
+	DefaultApplyTimeout = 30 * time.Second
+)
+
+type createPayload struct {
+	external.CreateActionRequest
+	// CreateActionRequest doesn't serialise its key, keep it so retries stay idempotent
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+type updatePayload struct {
+	ID     string                       `json:"id"`
+	Update external.UpdateActionRequest `json:"update"`
+}
+
+type deletePayload struct {
+	IDs []string `json:"ids"`
+}
+
+// NewCreate builds the outbox operation for creating an action
+func NewCreate(req *external.CreateActionRequest) (*store.Operation, error) {
+	return newOperation(store.OperationCreate, req.TaskID, createPayload{
+		CreateActionRequest: *req,
+		IdempotencyKey:      req.IdempotencyKey,
+	})
+}
+
+// NewUpdate builds the outbox operation for updating an action
+func NewUpdate(id string, req *external.UpdateActionRequest) (*store.Operation, error) {
+	return newOperation(store.OperationUpdate, id, updatePayload{ID: id, Update: *req})
+}
+
+// NewDelete builds the outbox operation for deleting actions in bulk
+func NewDelete(ids []string) (*store.Operation, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no ids to delete")
+	}
+	return newOperation(store.OperationDelete, ids[0], deletePayload{IDs: ids})
+}
+
+func newOperation(kind store.OperationKind, todoID string, payload any) (*store.Operation, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s operation: %w", kind, err)
+	}
+	return &store.Operation{
+		Kind:      kind,
+		TodoID:    todoID,
+		Payload:   payloadBytes,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// TodoIDs are the todos an operation touches, operations on the same todo must run in order
+func TodoIDs(op *store.Operation) []string {
+	if op.Kind != store.OperationDelete {
+		return []string{op.TodoID}
+	}
+	var payload deletePayload
+	if err := json.Unmarshal(op.Payload, &payload); err != nil {
+		return []string{op.TodoID}
+	}
+	return payload.IDs
+}
+
+// Worker drains the outbox. Only one drain runs at a time, so an operation is never sent twice at once.
+type Worker struct {
+	MaxAttempts  int
+	PollInterval time.Duration
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	ApplyTimeout time.Duration
+	// RequireToken fails operations nobody delegated a token to rather than making them with the shared one,
+	// e.g. those still queued when the server restarted
+	RequireToken bool
+
+	todos store.TodoStore
+	sc    external.ActionsAPI
+
+	// busy is held by the pass in progress, a channel rather than a mutex so waiting for it can be cancelled
+	busy chan struct{}
+	wake chan struct{}
+
+	tokenMu sync.Mutex
+	tokens  map[int64]string // SC tokens delegated to operations by their ID, only ever held in memory
+
+	sentMu sync.Mutex
+	sent   map[string]time.Time // when an operation on each todo was last sent to SC, see ChangedSince
+}
+
+func NewWorker(todos store.TodoStore, sc external.ActionsAPI) *Worker {
+	return &Worker{
+		MaxAttempts:  DefaultMaxAttempts,
+		PollInterval: DefaultPollInterval,
+		BaseDelay:    DefaultBaseDelay,
+		MaxDelay:     DefaultMaxDelay,
+		ApplyTimeout: DefaultApplyTimeout,
+		todos:        todos,
+		sc:           sc,
+		busy:         make(chan struct{}, 1),
+		wake:         make(chan struct{}, 1),
+		tokens:       make(map[int64]string),
+		sent:         make(map[string]time.Time),
+	}
+}
+
+// Delegate makes the operation with opID call SC with the caller's token rather than the shared one.
+// Call it inside the transaction that enqueues the operation, before it commits, so the operation
+// can't be applied with the shared token first, and call Forget if that transaction fails.
+// Tokens aren't persisted, operations still queued after a restart fall back to the shared token.
+func (w *Worker) Delegate(opID int64, token string) {
+	w.tokenMu.Lock()
+	defer w.tokenMu.Unlock()
+	// a rolled back id can be handed out again, so an empty token clears whatever was left on it
+	if token == "" {
+		delete(w.tokens, opID)
+		return
+	}
+	w.tokens[opID] = token
+}
+
+// Forget drops the token delegated to an operation whose transaction failed,
+// unless another operation has since been given the same id and a token of its own
+func (w *Worker) Forget(opID int64, token string) {
+	w.tokenMu.Lock()
+	defer w.tokenMu.Unlock()
+	if w.tokens[opID] == token {
+		delete(w.tokens, opID)
+	}
+}
+
+func (w *Worker) token(opID int64) string {
+	w.tokenMu.Lock()
+	defer w.tokenMu.Unlock()
+	return w.tokens[opID]
+}
+
+// forget drops the token of an operation that won't run again
+func (w *Worker) forget(opID int64) {
+	w.tokenMu.Lock()
+	defer w.tokenMu.Unlock()
+	delete(w.tokens, opID)
+}
+
+// ChangedSince returns the todos an operation was sent to SC for since the given time.
+// SC may or may not show the change yet, so anything read from SC in the meantime is stale for them.
+func (w *Worker) ChangedSince(since time.Time) map[string]bool {
+	w.sentMu.Lock()
+	defer w.sentMu.Unlock()
+
+	changed := map[string]bool{}
+	for id, at := range w.sent {
+		if time.Since(at) > sentRetention {
+			delete(w.sent, id)
+			continue
+		}
+		if !at.Before(since) {
+			changed[id] = true
+		}
+	}
+	return changed
+}
+
+func (w *Worker) markSent(ids []string) {
+	w.sentMu.Lock()
+	defer w.sentMu.Unlock()
+	now := time.Now()
+	for _, id := range ids {
+		w.sent[id] = now
+	}
+}
+
+// Run drains the outbox every PollInterval, or sooner after Notify, until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Drain(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to drain outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// Notify asks Run to drain the outbox now rather than at the next tick
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Drain applies every operation that is due
+func (w *Worker) Drain(ctx context.Context) error {
+	_, err := w.process(ctx, nil)
+	return err
+}
+
+// SyncTodos applies the due operations touching the given todos, so a handler can try to
+// reach SC before replying. Whatever is still pending is left for Run.
+// The results map each todo that was sent to SC to the error SC returned for it, nil if it was applied.
+func (w *Worker) SyncTodos(ctx context.Context, ids ...string) (map[string]error, error) {
+	wanted := map[string]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	return w.process(ctx, wanted)
+}
+
+// process applies due operations in order. Once an operation for a todo is skipped or fails,
+// later operations for that todo wait for the next pass so they can't overtake it.
+// Only one pass runs at a time, waiting for the one in progress gives up when ctx is done.
+func (w *Worker) process(ctx context.Context, wanted map[string]bool) (map[string]error, error) {
+	select {
+	case w.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-w.busy }()
+
+	ops, err := w.todos.PendingOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]error{}
+	blocked := map[string]bool{}
+	for _, op := range ops {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+
+		ids := TodoIDs(op)
+		if !touchesAny(ids, wanted) || anyBlocked(ids, blocked) || time.Now().Before(op.NextAttempt) {
+			block(ids, blocked)
+			continue
+		}
+
+		// marked whatever the outcome, a failed or timed out call may still have changed the action
+		err := w.applyWithTimeout(ctx, op)
+		w.markSent(ids)
+		if err != nil {
+			// the caller gave up mid-request, that isn't SC's fault so don't count the attempt
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+			var partial *partialDeleteError
+			if errors.As(err, &partial) {
+				// SC deleted some of the ids, only the rest are retried
+				if err := w.finishPartialDelete(ctx, op, partial, results); err != nil {
+					return results, err
+				}
+				ids = TodoIDs(op)
+			} else {
+				for _, id := range ids {
+					results[id] = err
+				}
+			}
+			block(ids, blocked)
+			if err := w.retryLater(ctx, op, err); err != nil {
+				return results, err
+			}
+			continue
+		}
+
+		for _, id := range ids {
+			results[id] = nil
+		}
+		w.forget(op.ID)
+		if op.Kind == store.OperationDelete {
+			if err := w.finishDelete(ctx, op.ID, ids); err != nil {
+				return results, err
+			}
+			continue
+		}
+		if err := w.todos.DeleteOperation(ctx, op.ID); err != nil {
+			return results, err
+		}
+		if err := w.setSyncState(ctx, ids, blocked, pb.SyncState_SYNC_STATE_SYNCED); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// finishDelete removes the todos SC has deleted from the store, together with their operation
+func (w *Worker) finishDelete(ctx context.Context, opID int64, ids []string) error {
+	return w.todos.Tx(ctx, func(tx store.TodoStore) error {
+		if err := tx.DeleteOperation(ctx, opID); err != nil {
+			return err
+		}
+		return deleteTodos(ctx, tx, ids)
+	})
+}
+
+// finishPartialDelete removes the todos SC did delete and narrows op down to the ids it refused
+func (w *Worker) finishPartialDelete(ctx context.Context, op *store.Operation, partial *partialDeleteError, results map[string]error) error {
+	var deleted, remaining []string
+	for _, id := range TodoIDs(op) {
+		results[id] = partial.errs[id]
+		if partial.errs[id] == nil {
+			deleted = append(deleted, id)
+		} else {
+			remaining = append(remaining, id)
+		}
+	}
+
+	payload, err := json.Marshal(deletePayload{IDs: remaining})
+	if err != nil {
+		return err
+	}
+	op.Payload = payload
+	op.TodoID = remaining[0]
+
+	return w.todos.Tx(ctx, func(tx store.TodoStore) error {
+		return deleteTodos(ctx, tx, deleted)
+	})
+}
+
+func deleteTodos(ctx context.Context, tx store.TodoStore, ids []string) error {
+	for _, id := range ids {
+		if err := tx.Delete(ctx, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) applyWithTimeout(ctx context.Context, op *store.Operation) error {
+	ctx, cancel := context.WithTimeout(ctx, w.ApplyTimeout)
+	defer cancel()
+	return w.apply(ctx, op)
+}
+
+// apply sends one operation to SC. Results that mean the change is already there count as success.
+func (w *Worker) apply(ctx context.Context, op *store.Operation) error {
+	// the operation runs as whoever queued it, not whoever's request happens to be syncing it
+	token := w.token(op.ID)
+	if token == "" && w.RequireToken {
+		return permanent(errors.New("the SafetyCulture token it was queued with is gone, the server restarted since"))
+	}
+	ctx = external.WithToken(ctx, token)
+
+	switch op.Kind {
+	case store.OperationCreate:
+		var payload createPayload
+		if err := json.Unmarshal(op.Payload, &payload); err != nil {
+			return permanent(err)
+		}
+		req := payload.CreateActionRequest
+		req.IdempotencyKey = payload.IdempotencyKey
+		_, err := w.sc.CreateAction(ctx, &req)
+		if status.Code(err) == codes.AlreadyExists {
+			return nil
+		}
+		return err
+
+	case store.OperationUpdate:
+		var payload updatePayload
+		if err := json.Unmarshal(op.Payload, &payload); err != nil {
+			return permanent(err)
+		}
+		return w.sc.UpdateAction(ctx, payload.ID, &payload.Update)
+
+	case store.OperationDelete:
+		var payload deletePayload
+		if err := json.Unmarshal(op.Payload, &payload); err != nil {
+			return permanent(err)
+		}
+		err := w.sc.DeleteActions(ctx, payload.IDs)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err == nil || retryable(err) || len(payload.IDs) == 1 {
+			return err
+		}
+		// SC refused the whole batch, so delete one at a time to find the ids it objects to
+		return w.deleteEach(ctx, payload.IDs)
+
+	default:
+		return permanent(fmt.Errorf("unknown operation kind %q", op.Kind))
+	}
+}
+
+// deleteEach deletes ids one by one, returning a *partialDeleteError naming the ones SC refused
+func (w *Worker) deleteEach(ctx context.Context, ids []string) error {
+	errs := map[string]error{}
+	for _, id := range ids {
+		err := w.sc.DeleteActions(ctx, []string{id})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && status.Code(err) != codes.NotFound {
+			errs[id] = err
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &partialDeleteError{errs: errs}
+}
+
+// retryLater schedules op again with backoff, or marks it failed when SC rejected it
+// or it has run out of attempts
+func (w *Worker) retryLater(ctx context.Context, op *store.Operation, applyErr error) error {
+	op.Attempts++
+	op.LastError = applyErr.Error()
+
+	state := pb.SyncState_SYNC_STATE_PENDING
+	if !retryable(applyErr) || op.Attempts >= w.MaxAttempts {
+		op.Failed = true
+		state = pb.SyncState_SYNC_STATE_FAILED
+		w.forget(op.ID)
+		log.Printf("Giving up on %s operation %d for todo %s: %v", op.Kind, op.ID, op.TodoID, applyErr)
+	} else {
+		op.NextAttempt = time.Now().Add(w.backoff(op.Attempts))
+	}
+
+	if err := w.todos.UpdateOperation(ctx, op); err != nil {
+		return err
+	}
+	return w.setSyncState(ctx, TodoIDs(op), nil, state)
+}
+
+// setSyncState records state on the todos that still exist.
+// A todo with another operation queued behind this one stays pending.
+func (w *Worker) setSyncState(ctx context.Context, ids []string, blocked map[string]bool, state pb.SyncState) error {
+	return w.todos.Tx(ctx, func(tx store.TodoStore) error {
+		for _, id := range ids {
+			if state == pb.SyncState_SYNC_STATE_SYNCED && blocked[id] {
+				continue
+			}
+			todo, err := tx.Get(ctx, id)
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if todo.GetSyncState() == state {
+				continue
+			}
+			todo.SyncState = state
+			if err := tx.Put(ctx, todo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backoff returns the delay before the given attempt, doubling from BaseDelay up to MaxDelay with jitter
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.BaseDelay << (attempts - 1)
+	if delay <= 0 || delay > w.MaxDelay {
+		delay = w.MaxDelay
+	}
+	// jitter between half and the full delay so queued operations don't retry in lockstep
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// partialDeleteError holds the SC error for each id a bulk delete couldn't remove
+type partialDeleteError struct {
+	errs map[string]error
+}
+
+func (e *partialDeleteError) Error() string {
+	ids := make([]string, 0, len(e.errs))
+	for id := range e.errs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	msgs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", id, e.errs[id]))
+	}
+	return fmt.Sprintf("SafetyCulture refused to delete %d actions: %s", len(ids), strings.Join(msgs, "; "))
+}
+
+type permanentError struct{ error }
+
+func permanent(err error) error {
+	return permanentError{err}
+}
+
+// retryable reports whether SC might accept the operation later
+func retryable(err error) bool {
+	var p permanentError
+	if errors.As(err, &p) {
+		return false
+	}
+	// worth retrying if any of the ids might still go through
+	var partial *partialDeleteError
+	if errors.As(err, &partial) {
+		for _, idErr := range partial.errs {
+			if retryable(idErr) {
+				return true
+			}
+		}
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Canceled,
+		codes.Aborted, codes.Internal, codes.Unknown, codes.Unauthenticated:
+		return true
+	}
+	return false
+}
+
+func touchesAny(ids []string, wanted map[string]bool) bool {
+	if wanted == nil {
+		return true
+	}
+	for _, id := range ids {
+		if wanted[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func anyBlocked(ids []string, blocked map[string]bool) bool {
+	for _, id := range ids {
+		if blocked[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func block(ids []string, blocked map[string]bool) {
+	for _, id := range ids {
+		blocked[id] = true
+	}
+}
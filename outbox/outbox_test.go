@@ -0,0 +1,310 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/external/scfake"
+	"github.com/jerryhong21/todo-grpc/outbox"
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+const sharedToken = "shared-token"
+
+type env struct {
+	todos  *store.MemoryStore
+	fake   *scfake.Server
+	worker *outbox.Worker
+}
+
+// newEnv wires a worker to a fake SC, the SC client doesn't retry by itself so every attempt is the outbox's
+func newEnv(t *testing.T, fakeToken string) *env {
+	fake := scfake.New(fakeToken)
+	t.Cleanup(fake.Close)
+	sc := external.NewSCClient(fake.URL, sharedToken)
+	sc.HTTPClient.Transport.(*external.BreakerTransport).Base.(*external.RetryTransport).MaxAttempts = 1
+	sc.Breaker.FailureThreshold = 1000
+
+	todos := store.NewMemoryStore()
+	worker := outbox.NewWorker(todos, sc)
+	worker.BaseDelay = time.Millisecond
+	worker.MaxDelay = time.Millisecond
+	return &env{todos: todos, fake: fake, worker: worker}
+}
+
+// create stores a pending todo and queues its create, like CreateTodo does
+func (e *env) create(t *testing.T, id string) *store.Operation {
+	t.Helper()
+	op, err := outbox.NewCreate(&external.CreateActionRequest{TaskID: id, Title: "title " + id, IdempotencyKey: "create-" + id})
+	if err != nil {
+		t.Fatalf("NewCreate: %v", err)
+	}
+	e.queue(t, &pb.Todo{Id: id, Title: "title " + id, SyncState: pb.SyncState_SYNC_STATE_PENDING, Version: 1}, op)
+	return op
+}
+
+func (e *env) queue(t *testing.T, todo *pb.Todo, op *store.Operation) {
+	t.Helper()
+	ctx := context.Background()
+	err := e.todos.Tx(ctx, func(tx store.TodoStore) error {
+		if todo != nil {
+			if err := tx.Put(ctx, todo); err != nil {
+				return err
+			}
+		}
+		return tx.Enqueue(ctx, op)
+	})
+	if err != nil {
+		t.Fatalf("queueing %s operation: %v", op.Kind, err)
+	}
+}
+
+func (e *env) syncState(t *testing.T, id string) pb.SyncState {
+	t.Helper()
+	todo, err := e.todos.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get %s: %v", id, err)
+	}
+	return todo.GetSyncState()
+}
+
+func (e *env) pending(t *testing.T) []*store.Operation {
+	t.Helper()
+	ops, err := e.todos.PendingOperations(context.Background())
+	if err != nil {
+		t.Fatalf("PendingOperations: %v", err)
+	}
+	return ops
+}
+
+func TestDrainAppliesInOrder(t *testing.T) {
+	e := newEnv(t, sharedToken)
+	ctx := context.Background()
+
+	e.create(t, "a")
+	update, err := outbox.NewUpdate("a", &external.UpdateActionRequest{Title: proto.String("renamed")})
+	if err != nil {
+		t.Fatalf("NewUpdate: %v", err)
+	}
+	e.queue(t, nil, update)
+
+	if err := e.worker.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	action, ok := e.fake.Action("a")
+	if !ok || action.Title != "renamed" {
+		t.Fatalf("SC has %+v, want the action created then renamed", action)
+	}
+	if state := e.syncState(t, "a"); state != pb.SyncState_SYNC_STATE_SYNCED {
+		t.Fatalf("todo is %s, want synced", state)
+	}
+	if ops := e.pending(t); len(ops) != 0 {
+		t.Fatalf("%d operations left after draining", len(ops))
+	}
+}
+
+func TestDrainRetriesTransientFailures(t *testing.T) {
+	e := newEnv(t, sharedToken)
+	ctx := context.Background()
+	e.create(t, "a")
+	update, _ := outbox.NewUpdate("a", &external.UpdateActionRequest{Title: proto.String("renamed")})
+	e.queue(t, nil, update)
+
+	e.fake.InjectFault(scfake.Fault{Method: http.MethodPost, Status: http.StatusServiceUnavailable, Code: codes.Unavailable, Times: 1})
+	if err := e.worker.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	ops := e.pending(t)
+	if len(ops) != 2 || ops[0].Attempts != 1 || ops[0].LastError == "" {
+		t.Fatalf("after a failed create the queue is %+v, want the create retried and the update behind it", ops)
+	}
+	if _, ok := e.fake.Action("a"); ok {
+		t.Fatal("the update overtook the failed create")
+	}
+	if state := e.syncState(t, "a"); state != pb.SyncState_SYNC_STATE_PENDING {
+		t.Fatalf("todo is %s, want pending while it is retried", state)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if err := e.worker.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if action, ok := e.fake.Action("a"); !ok || action.Title != "renamed" {
+		t.Fatalf("SC has %+v after the retry, want the renamed action", action)
+	}
+	if state := e.syncState(t, "a"); state != pb.SyncState_SYNC_STATE_SYNCED {
+		t.Fatalf("todo is %s, want synced", state)
+	}
+}
+
+func TestDrainGivesUp(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault scfake.Fault
+		setup func(w *outbox.Worker)
+	}{
+		{
+			name:  "when SC rejects the change",
+			fault: scfake.Fault{Status: http.StatusBadRequest, Code: codes.InvalidArgument},
+		},
+		{
+			name:  "after MaxAttempts",
+			fault: scfake.Fault{Status: http.StatusServiceUnavailable, Code: codes.Unavailable},
+			setup: func(w *outbox.Worker) { w.MaxAttempts = 1 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEnv(t, sharedToken)
+			if tt.setup != nil {
+				tt.setup(e.worker)
+			}
+			e.create(t, "a")
+			e.fake.InjectFault(tt.fault)
+
+			if err := e.worker.Drain(context.Background()); err != nil {
+				t.Fatalf("Drain: %v", err)
+			}
+			if state := e.syncState(t, "a"); state != pb.SyncState_SYNC_STATE_FAILED {
+				t.Fatalf("todo is %s, want failed", state)
+			}
+			if ops := e.pending(t); len(ops) != 0 {
+				t.Fatalf("a failed operation is still pending: %+v", ops)
+			}
+		})
+	}
+}
+
+func TestDrainDeletes(t *testing.T) {
+	e := newEnv(t, sharedToken)
+	ctx := context.Background()
+	e.create(t, "a")
+	e.create(t, "b")
+	if err := e.worker.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	del, err := outbox.NewDelete([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("NewDelete: %v", err)
+	}
+	e.queue(t, nil, del)
+	// SC refuses the batch, so the ids are deleted one at a time
+	e.fake.InjectFault(scfake.Fault{PathPrefix: "/tasks/v1/actions/delete", Status: http.StatusForbidden, Code: codes.PermissionDenied, Times: 1})
+
+	results, err := e.worker.SyncTodos(ctx, "a", "b")
+	if err != nil {
+		t.Fatalf("SyncTodos: %v", err)
+	}
+	if len(results) != 2 || results["a"] != nil || results["b"] != nil {
+		t.Fatalf("SyncTodos returned %v, want both deleted", results)
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, ok := e.fake.Action(id); ok {
+			t.Fatalf("action %s is still in SC", id)
+		}
+		if _, err := e.todos.Get(ctx, id); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("todo %s is still stored: %v", id, err)
+		}
+	}
+}
+
+func TestDelegatedTokens(t *testing.T) {
+	// the fake only accepts the caller's token, so the shared one can't be used by mistake
+	e := newEnv(t, "caller-token")
+	ctx := context.Background()
+
+	op := e.create(t, "a")
+	e.worker.Delegate(op.ID, "caller-token")
+	if err := e.worker.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if _, ok := e.fake.Action("a"); !ok {
+		t.Fatal("the create didn't reach SC with the delegated token")
+	}
+
+	// an operation nobody delegated a token to fails rather than running as the shared account
+	e.worker.RequireToken = true
+	e.create(t, "b")
+	before := e.fake.Requests()
+	if err := e.worker.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if e.fake.Requests() != before {
+		t.Fatal("an operation without a token was sent to SC")
+	}
+	if state := e.syncState(t, "b"); state != pb.SyncState_SYNC_STATE_FAILED {
+		t.Fatalf("todo is %s, want failed", state)
+	}
+}
+
+func TestSyncTodosStopsAtDeadline(t *testing.T) {
+	e := newEnv(t, sharedToken)
+	e.worker.ApplyTimeout = 5 * time.Second
+	e.create(t, "slow")
+	e.fake.SetLatency(time.Second)
+
+	// a background drain is stuck on the slow call
+	drained := make(chan error, 1)
+	go func() { drained <- e.worker.Drain(context.Background()) }()
+	time.Sleep(50 * time.Millisecond)
+
+	e.create(t, "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := e.worker.SyncTodos(ctx, "a")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SyncTodos returned %v, want it to give up at the deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("SyncTodos waited %s for the drain in progress, its deadline was 200ms", elapsed)
+	}
+
+	e.fake.SetLatency(0)
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+}
+
+func TestApplyTimeout(t *testing.T) {
+	e := newEnv(t, sharedToken)
+	e.worker.ApplyTimeout = 100 * time.Millisecond
+	e.create(t, "a")
+	e.fake.SetLatency(3 * time.Second)
+
+	start := time.Now()
+	if err := e.worker.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Drain took %s, ApplyTimeout is 100ms", elapsed)
+	}
+	ops := e.pending(t)
+	if len(ops) != 1 || ops[0].Attempts != 1 {
+		t.Fatalf("queue is %+v after a timed out call, want the operation kept for another attempt", ops)
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	e := newEnv(t, sharedToken)
+	before := time.Now()
+	e.create(t, "a")
+	if err := e.worker.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	if changed := e.worker.ChangedSince(before); !changed["a"] {
+		t.Fatalf("ChangedSince(before the drain) = %v, want a", changed)
+	}
+	if changed := e.worker.ChangedSince(time.Now()); changed["a"] {
+		t.Fatalf("ChangedSince(after the drain) = %v, want nothing", changed)
+	}
+}
@@ -0,0 +1,183 @@
+// Package policy decides which authenticated callers may call which RPCs.
+//
+// A policy file names the permission each method needs and the permissions each role grants.
+// Callers get their roles from their credentials, see auth.Identity. A call is allowed when
+// one of the caller's roles grants the method's permission, otherwise it fails with
+// PERMISSION_DENIED and an ErrorInfo detail naming the missing permission.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jerryhong21/todo-grpc/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// ReasonMissingPermission is the ErrorInfo reason of a denied call, its metadata has the permission.
+// Clients match it by value, so it must not change.
+const ReasonMissingPermission = "MISSING_PERMISSION"
+
+// ErrorDomain is the ErrorInfo domain of a denied call
+const ErrorDomain = "todo-grpc"
+
+// file is the policy file:
+//
+//	roles:
+//	  viewer:
+//	    permissions: [todos.get, todos.list]
+//	  editor:
+//	    includes: [viewer]
+//	    permissions: [todos.create, todos.update]
+//	methods:
+//	  /todo.TodoService/GetTodo: todos.get
+type file struct {
+	Roles map[string]struct {
+		Includes    []string `yaml:"includes"`
+		Permissions []string `yaml:"permissions"`
+	} `yaml:"roles"`
+	Methods map[string]string `yaml:"methods"`
+}
+
+// Engine checks calls against a policy loaded at startup
+type Engine struct {
+	roles   map[string]map[string]bool // role to every permission it grants, includes resolved
+	methods map[string]string          // full method name to the permission it needs
+}
+
+// Load reads the policy file at path
+func Load(path string) (*Engine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open policy file: %w", err)
+	}
+	defer f.Close()
+
+	var policy file
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to read policy file %s: %w", path, err)
+	}
+
+	e := &Engine{roles: map[string]map[string]bool{}, methods: map[string]string{}}
+	for method, permission := range policy.Methods {
+		if !strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2 {
+			return nil, fmt.Errorf("method %q in %s must be a full method name like /todo.TodoService/GetTodo", method, path)
+		}
+		if permission == "" {
+			return nil, fmt.Errorf("method %s in %s needs a permission", method, path)
+		}
+		e.methods[method] = permission
+	}
+
+	// resolve includes depth first, visiting marks roles on the current path to catch cycles
+	visiting := map[string]bool{}
+	var resolve func(role string) (map[string]bool, error)
+	resolve = func(role string) (map[string]bool, error) {
+		if granted, ok := e.roles[role]; ok {
+			return granted, nil
+		}
+		def, ok := policy.Roles[role]
+		if !ok {
+			return nil, fmt.Errorf("role %q in %s isn't defined", role, path)
+		}
+		if visiting[role] {
+			return nil, fmt.Errorf("role %q in %s includes itself", role, path)
+		}
+		visiting[role] = true
+		defer delete(visiting, role)
+
+		granted := map[string]bool{}
+		for _, permission := range def.Permissions {
+			granted[permission] = true
+		}
+		for _, included := range def.Includes {
+			more, err := resolve(included)
+			if err != nil {
+				return nil, err
+			}
+			for permission := range more {
+				granted[permission] = true
+			}
+		}
+		e.roles[role] = granted
+		return granted, nil
+	}
+	for role := range policy.Roles {
+		if _, err := resolve(role); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Unmapped returns the methods of sd that the policy doesn't name a permission for
+func (e *Engine) Unmapped(sd *grpc.ServiceDesc) []string {
+	var missing []string
+	for _, m := range sd.Methods {
+		if name := "/" + sd.ServiceName + "/" + m.MethodName; e.methods[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	for _, s := range sd.Streams {
+		if name := "/" + sd.ServiceName + "/" + s.StreamName; e.methods[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Unary is a grpc.UnaryServerInterceptor, it must run after auth's
+func (e *Engine) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := e.Authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// Stream is a grpc.StreamServerInterceptor, it must run after auth's
+func (e *Engine) Stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := e.Authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// Authorize returns nil if the caller in ctx may call method, a PERMISSION_DENIED error otherwise.
+// Methods the policy doesn't mention are denied to everyone.
+func (e *Engine) Authorize(ctx context.Context, method string) error {
+	permission, ok := e.methods[method]
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "no permission is defined for %s", method)
+	}
+	// without an identity there are no roles, so everything mapped is denied
+	if id, ok := auth.FromContext(ctx); ok {
+		for _, role := range id.Roles {
+			if e.roles[role][permission] {
+				return nil
+			}
+		}
+	}
+	return denied(method, permission)
+}
+
+func denied(method, permission string) error {
+	st := status.Newf(codes.PermissionDenied, "%s needs the %s permission, which none of your roles grant", method, permission)
+	if withDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   ReasonMissingPermission,
+		Domain:   ErrorDomain,
+		Metadata: map[string]string{"permission": permission},
+	}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
@@ -0,0 +1,152 @@
+package policy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jerryhong21/todo-grpc/auth"
+	"github.com/jerryhong21/todo-grpc/policy"
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testPolicy = `
+roles:
+  viewer:
+    permissions: [todos.get, todos.list]
+  editor:
+    includes: [viewer]
+    permissions: [todos.create, todos.update]
+  admin:
+    includes: [editor]
+    permissions: [todos.delete]
+methods:
+  /todo.TodoService/GetTodo: todos.get
+  /todo.TodoService/ListTodos: todos.list
+  /todo.TodoService/CreateTodo: todos.create
+  /todo.TodoService/BulkDeleteTodo: todos.delete
+`
+
+func load(t *testing.T, content string) (*policy.Engine, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return policy.Load(path)
+}
+
+func TestAuthorize(t *testing.T) {
+	e, err := load(t, testPolicy)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		roles          []string // nil means the caller isn't authenticated
+		method         string
+		wantCode       codes.Code
+		wantPermission string // named in the ErrorInfo of a denial
+	}{
+		{name: "granted directly", roles: []string{"viewer"}, method: "/todo.TodoService/GetTodo", wantCode: codes.OK},
+		{name: "granted by an included role", roles: []string{"editor"}, method: "/todo.TodoService/ListTodos", wantCode: codes.OK},
+		{name: "granted two includes down", roles: []string{"admin"}, method: "/todo.TodoService/GetTodo", wantCode: codes.OK},
+		{name: "granted by any of the roles", roles: []string{"viewer", "admin"}, method: "/todo.TodoService/BulkDeleteTodo", wantCode: codes.OK},
+		{name: "missing permission", roles: []string{"viewer"}, method: "/todo.TodoService/CreateTodo", wantCode: codes.PermissionDenied, wantPermission: "todos.create"},
+		{name: "includes only go one way", roles: []string{"editor"}, method: "/todo.TodoService/BulkDeleteTodo", wantCode: codes.PermissionDenied, wantPermission: "todos.delete"},
+		{name: "unknown role", roles: []string{"superuser"}, method: "/todo.TodoService/GetTodo", wantCode: codes.PermissionDenied, wantPermission: "todos.get"},
+		{name: "no roles", roles: []string{}, method: "/todo.TodoService/GetTodo", wantCode: codes.PermissionDenied, wantPermission: "todos.get"},
+		{name: "not authenticated", method: "/todo.TodoService/GetTodo", wantCode: codes.PermissionDenied, wantPermission: "todos.get"},
+		{name: "unmapped method", roles: []string{"admin"}, method: "/todo.TodoService/UpdateTodo", wantCode: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.roles != nil {
+				ctx = auth.NewContext(ctx, &auth.Identity{Subject: "alice", Roles: tt.roles, Method: auth.MethodAPIKey})
+			}
+			err := e.Authorize(ctx, tt.method)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Authorize returned %v, want %v", err, tt.wantCode)
+			}
+			if tt.wantPermission == "" {
+				return
+			}
+			var info *errdetails.ErrorInfo
+			for _, d := range status.Convert(err).Details() {
+				if i, ok := d.(*errdetails.ErrorInfo); ok {
+					info = i
+				}
+			}
+			if info == nil || info.GetReason() != policy.ReasonMissingPermission || info.GetDomain() != policy.ErrorDomain ||
+				info.GetMetadata()["permission"] != tt.wantPermission {
+				t.Fatalf("denial carries %v, want an ErrorInfo naming %s", info, tt.wantPermission)
+			}
+		})
+	}
+}
+
+func TestUnmapped(t *testing.T) {
+	e, err := load(t, testPolicy)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got := e.Unmapped(&pb.TodoService_ServiceDesc)
+	for _, mapped := range []string{"/todo.TodoService/GetTodo", "/todo.TodoService/ListTodos"} {
+		if slices.Contains(got, mapped) {
+			t.Fatalf("Unmapped returned %s, which the policy maps", mapped)
+		}
+	}
+	if !slices.Contains(got, "/todo.TodoService/UpdateTodo") || !slices.IsSorted(got) {
+		t.Fatalf("Unmapped returned %v, want the unmapped methods sorted", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "undefined include",
+			content: "roles:\n  editor:\n    includes: [viewer]\n",
+			wantErr: `role "viewer"`,
+		},
+		{
+			name:    "cycle",
+			content: "roles:\n  a:\n    includes: [b]\n  b:\n    includes: [a]\n",
+			wantErr: "includes itself",
+		},
+		{
+			name:    "method that isn't a full name",
+			content: "methods:\n  GetTodo: todos.get\n",
+			wantErr: "full method name",
+		},
+		{
+			name:    "method without a permission",
+			content: "methods:\n  /todo.TodoService/GetTodo: \"\"\n",
+			wantErr: "needs a permission",
+		},
+		{
+			name:    "unknown field",
+			content: "rules: []\n",
+			wantErr: "failed to read policy file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -11,7 +11,6 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
-	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -25,6 +24,9 @@ const (
 	TodoService_UpdateTodo_FullMethodName     = "/todo.TodoService/UpdateTodo"
 	TodoService_BulkDeleteTodo_FullMethodName = "/todo.TodoService/BulkDeleteTodo"
 	TodoService_ListTodos_FullMethodName      = "/todo.TodoService/ListTodos"
+	TodoService_SyncNow_FullMethodName        = "/todo.TodoService/SyncNow"
+	TodoService_AssignTodo_FullMethodName     = "/todo.TodoService/AssignTodo"
+	TodoService_ShareTodo_FullMethodName      = "/todo.TodoService/ShareTodo"
 )
 
 // TodoServiceClient is the client API for TodoService service.
@@ -34,8 +36,13 @@ type TodoServiceClient interface {
 	CreateTodo(ctx context.Context, in *CreateTodoRequest, opts ...grpc.CallOption) (*Todo, error)
 	GetTodo(ctx context.Context, in *GetTodoRequest, opts ...grpc.CallOption) (*Todo, error)
 	UpdateTodo(ctx context.Context, in *UpdateTodoRequest, opts ...grpc.CallOption) (*Todo, error)
-	BulkDeleteTodo(ctx context.Context, in *BulkDeleteTodoRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
-	ListTodos(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Todo], error)
+	BulkDeleteTodo(ctx context.Context, in *BulkDeleteTodoRequest, opts ...grpc.CallOption) (*BulkDeleteTodoResponse, error)
+	ListTodos(ctx context.Context, in *ListTodosRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Todo], error)
+	// Reconciles the local store with SafetyCulture straight away instead of
+	// waiting for the next periodic run.
+	SyncNow(ctx context.Context, in *SyncNowRequest, opts ...grpc.CallOption) (*SyncNowResponse, error)
+	AssignTodo(ctx context.Context, in *AssignTodoRequest, opts ...grpc.CallOption) (*Todo, error)
+	ShareTodo(ctx context.Context, in *ShareTodoRequest, opts ...grpc.CallOption) (*Todo, error)
 }
 
 type todoServiceClient struct {
@@ -76,9 +83,9 @@ func (c *todoServiceClient) UpdateTodo(ctx context.Context, in *UpdateTodoReques
 	return out, nil
 }
 
-func (c *todoServiceClient) BulkDeleteTodo(ctx context.Context, in *BulkDeleteTodoRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+func (c *todoServiceClient) BulkDeleteTodo(ctx context.Context, in *BulkDeleteTodoRequest, opts ...grpc.CallOption) (*BulkDeleteTodoResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(emptypb.Empty)
+	out := new(BulkDeleteTodoResponse)
 	err := c.cc.Invoke(ctx, TodoService_BulkDeleteTodo_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
@@ -86,13 +93,13 @@ func (c *todoServiceClient) BulkDeleteTodo(ctx context.Context, in *BulkDeleteTo
 	return out, nil
 }
 
-func (c *todoServiceClient) ListTodos(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Todo], error) {
+func (c *todoServiceClient) ListTodos(ctx context.Context, in *ListTodosRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Todo], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &TodoService_ServiceDesc.Streams[0], TodoService_ListTodos_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &grpc.GenericClientStream[emptypb.Empty, Todo]{ClientStream: stream}
+	x := &grpc.GenericClientStream[ListTodosRequest, Todo]{ClientStream: stream}
 	if err := x.ClientStream.SendMsg(in); err != nil {
 		return nil, err
 	}
@@ -105,6 +112,36 @@ func (c *todoServiceClient) ListTodos(ctx context.Context, in *emptypb.Empty, op
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type TodoService_ListTodosClient = grpc.ServerStreamingClient[Todo]
 
+func (c *todoServiceClient) SyncNow(ctx context.Context, in *SyncNowRequest, opts ...grpc.CallOption) (*SyncNowResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncNowResponse)
+	err := c.cc.Invoke(ctx, TodoService_SyncNow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) AssignTodo(ctx context.Context, in *AssignTodoRequest, opts ...grpc.CallOption) (*Todo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Todo)
+	err := c.cc.Invoke(ctx, TodoService_AssignTodo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) ShareTodo(ctx context.Context, in *ShareTodoRequest, opts ...grpc.CallOption) (*Todo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Todo)
+	err := c.cc.Invoke(ctx, TodoService_ShareTodo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TodoServiceServer is the server API for TodoService service.
 // All implementations must embed UnimplementedTodoServiceServer
 // for forward compatibility.
@@ -112,8 +149,13 @@ type TodoServiceServer interface {
 	CreateTodo(context.Context, *CreateTodoRequest) (*Todo, error)
 	GetTodo(context.Context, *GetTodoRequest) (*Todo, error)
 	UpdateTodo(context.Context, *UpdateTodoRequest) (*Todo, error)
-	BulkDeleteTodo(context.Context, *BulkDeleteTodoRequest) (*emptypb.Empty, error)
-	ListTodos(*emptypb.Empty, grpc.ServerStreamingServer[Todo]) error
+	BulkDeleteTodo(context.Context, *BulkDeleteTodoRequest) (*BulkDeleteTodoResponse, error)
+	ListTodos(*ListTodosRequest, grpc.ServerStreamingServer[Todo]) error
+	// Reconciles the local store with SafetyCulture straight away instead of
+	// waiting for the next periodic run.
+	SyncNow(context.Context, *SyncNowRequest) (*SyncNowResponse, error)
+	AssignTodo(context.Context, *AssignTodoRequest) (*Todo, error)
+	ShareTodo(context.Context, *ShareTodoRequest) (*Todo, error)
 	mustEmbedUnimplementedTodoServiceServer()
 }
 
@@ -133,12 +175,21 @@ func (UnimplementedTodoServiceServer) GetTodo(context.Context, *GetTodoRequest)
 func (UnimplementedTodoServiceServer) UpdateTodo(context.Context, *UpdateTodoRequest) (*Todo, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateTodo not implemented")
 }
-func (UnimplementedTodoServiceServer) BulkDeleteTodo(context.Context, *BulkDeleteTodoRequest) (*emptypb.Empty, error) {
+func (UnimplementedTodoServiceServer) BulkDeleteTodo(context.Context, *BulkDeleteTodoRequest) (*BulkDeleteTodoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BulkDeleteTodo not implemented")
 }
-func (UnimplementedTodoServiceServer) ListTodos(*emptypb.Empty, grpc.ServerStreamingServer[Todo]) error {
+func (UnimplementedTodoServiceServer) ListTodos(*ListTodosRequest, grpc.ServerStreamingServer[Todo]) error {
 	return status.Errorf(codes.Unimplemented, "method ListTodos not implemented")
 }
+func (UnimplementedTodoServiceServer) SyncNow(context.Context, *SyncNowRequest) (*SyncNowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncNow not implemented")
+}
+func (UnimplementedTodoServiceServer) AssignTodo(context.Context, *AssignTodoRequest) (*Todo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AssignTodo not implemented")
+}
+func (UnimplementedTodoServiceServer) ShareTodo(context.Context, *ShareTodoRequest) (*Todo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShareTodo not implemented")
+}
 func (UnimplementedTodoServiceServer) mustEmbedUnimplementedTodoServiceServer() {}
 func (UnimplementedTodoServiceServer) testEmbeddedByValue()                     {}
 
@@ -233,16 +284,70 @@ func _TodoService_BulkDeleteTodo_Handler(srv interface{}, ctx context.Context, d
 }
 
 func _TodoService_ListTodos_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(emptypb.Empty)
+	m := new(ListTodosRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
-	return srv.(TodoServiceServer).ListTodos(m, &grpc.GenericServerStream[emptypb.Empty, Todo]{ServerStream: stream})
+	return srv.(TodoServiceServer).ListTodos(m, &grpc.GenericServerStream[ListTodosRequest, Todo]{ServerStream: stream})
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type TodoService_ListTodosServer = grpc.ServerStreamingServer[Todo]
 
+func _TodoService_SyncNow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncNowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).SyncNow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TodoService_SyncNow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).SyncNow(ctx, req.(*SyncNowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_AssignTodo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssignTodoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).AssignTodo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TodoService_AssignTodo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).AssignTodo(ctx, req.(*AssignTodoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_ShareTodo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShareTodoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).ShareTodo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TodoService_ShareTodo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).ShareTodo(ctx, req.(*ShareTodoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TodoService_ServiceDesc is the grpc.ServiceDesc for TodoService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -266,6 +371,18 @@ var TodoService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "BulkDeleteTodo",
 			Handler:    _TodoService_BulkDeleteTodo_Handler,
 		},
+		{
+			MethodName: "SyncNow",
+			Handler:    _TodoService_SyncNow_Handler,
+		},
+		{
+			MethodName: "AssignTodo",
+			Handler:    _TodoService_AssignTodo_Handler,
+		},
+		{
+			MethodName: "ShareTodo",
+			Handler:    _TodoService_ShareTodo_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -0,0 +1,371 @@
+// Package reconcile brings the local todo store back in line with SafetyCulture.
+//
+// Actions can be created, edited or deleted directly in the SC web app, which the
+// server never hears about. The Reconciler lists every SC action, imports the ones
+// missing locally, refreshes synced todos that changed in SC, tombstones todos whose
+// action is gone, and reports conflicts where both sides changed.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/outbox"
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultInterval is how often Run reconciles
+const DefaultInterval = 5 * time.Minute
+
+// listPageSize is the number of actions fetched per ListActions call
+const listPageSize = 100
+
+type Conflict struct {
+	ID     string
+	Reason string
+}
+
+// Counts are the todos a reconciliation changed
+type Counts struct {
+	Imported   int
+	Updated    int
+	Tombstoned int
+}
+
+// Report summarises one reconciliation
+type Report struct {
+	Counts                        // across every tenant
+	PerTenant  map[string]*Counts // by the tenant of the todos, see Tenant
+	Conflicts  []Conflict
+	FinishedAt time.Time
+}
+
+// Tenant returns the counts for the todos of one tenant
+func (r *Report) Tenant(tenant string) Counts {
+	if c, ok := r.PerTenant[tenant]; ok {
+		return *c
+	}
+	return Counts{}
+}
+
+func (r *Report) counts(tenant string) *Counts {
+	if r.PerTenant == nil {
+		r.PerTenant = map[string]*Counts{}
+	}
+	if _, ok := r.PerTenant[tenant]; !ok {
+		r.PerTenant[tenant] = &Counts{}
+	}
+	return r.PerTenant[tenant]
+}
+
+type Reconciler struct {
+	Interval time.Duration
+
+	todos  store.TodoStore
+	sc     external.ActionsAPI
+	outbox *outbox.Worker
+
+	mu sync.Mutex // one reconciliation at a time
+}
+
+func NewReconciler(todos store.TodoStore, sc external.ActionsAPI, worker *outbox.Worker) *Reconciler {
+	return &Reconciler{
+		Interval: DefaultInterval,
+		todos:    todos,
+		sc:       sc,
+		outbox:   worker,
+	}
+}
+
+// Run reconciles every Interval until ctx is done
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := r.Reconcile(ctx)
+		if err != nil {
+			log.Printf("Reconciliation with SafetyCulture failed: %v", err)
+			continue
+		}
+		log.Printf("Reconciled with SafetyCulture: %d imported, %d updated, %d tombstoned, %d conflicts",
+			report.Imported, report.Updated, report.Tombstoned, len(report.Conflicts))
+		for _, c := range report.Conflicts {
+			log.Printf("Reconciliation conflict on todo %s: %s", c.ID, c.Reason)
+		}
+	}
+}
+
+// Reconcile compares every SC action with the local store and applies the differences
+func (r *Reconciler) Reconcile(ctx context.Context) (*Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// push our own queued changes first so they aren't mistaken for drift
+	if err := r.outbox.Drain(ctx); err != nil {
+		return nil, fmt.Errorf("failed to drain outbox: %w", err)
+	}
+
+	started := time.Now()
+	actions, err := r.listAllActions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	err = r.todos.Tx(ctx, func(tx store.TodoStore) error {
+		// read inside the transaction, todos created or changed while SC was listed aren't in actions
+		queued, err := r.unsettled(ctx, tx, started)
+		if err != nil {
+			return err
+		}
+		local, err := tx.List(ctx)
+		if err != nil {
+			return err
+		}
+		localByID := make(map[string]*pb.Todo, len(local))
+		for _, todo := range local {
+			localByID[todo.GetId()] = todo
+		}
+
+		for id, action := range actions {
+			if queued[id] {
+				// a local change is still on its way to SC, the next run will see the result
+				continue
+			}
+			if err := applyAction(ctx, tx, localByID[id], action, report); err != nil {
+				return err
+			}
+		}
+
+		for id, todo := range localByID {
+			if _, inSC := actions[id]; inSC || queued[id] {
+				continue
+			}
+			if err := applyRemoved(ctx, tx, todo, report); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply reconciliation: %w", err)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// Apply brings a single todo in line with its SC action, e.g. when a webhook says it changed.
+// SC is asked for the current action rather than trusting the event, so late or repeated events are harmless.
+func (r *Reconciler) Apply(ctx context.Context, id string) (*Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{}
+	started := time.Now()
+	action, err := r.sc.GetAction(ctx, id)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+
+	err = r.todos.Tx(ctx, func(tx store.TodoStore) error {
+		queued, err := r.unsettled(ctx, tx, started)
+		if err != nil {
+			return err
+		}
+		if queued[id] {
+			// a local change is still on its way to SC, the next run will see the result
+			return nil
+		}
+		todo, err := tx.Get(ctx, id)
+		if err != nil && err != store.ErrNotFound {
+			return err
+		}
+		if action != nil {
+			return applyAction(ctx, tx, todo, action, report)
+		}
+		if todo != nil {
+			return applyRemoved(ctx, tx, todo, report)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply SafetyCulture change to todo %s: %w", id, err)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// Fetch imports a single action that is missing from the local store.
+// It returns store.ErrNotFound if SC doesn't have the action either, or if a local change is still settling,
+// and the local todo if one was created meanwhile.
+func (r *Reconciler) Fetch(ctx context.Context, id string) (*pb.Todo, error) {
+	started := time.Now()
+	action, err := r.sc.GetAction(ctx, id)
+	if status.Code(err) == codes.NotFound {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var todo *pb.Todo
+	err = r.todos.Tx(ctx, func(tx store.TodoStore) error {
+		queued, err := r.unsettled(ctx, tx, started)
+		if err != nil {
+			return err
+		}
+		if queued[id] {
+			return store.ErrNotFound
+		}
+		todo, err = tx.Get(ctx, id)
+		if err != store.ErrNotFound {
+			return err
+		}
+		todo = todoFromAction(action)
+		todo.Version = 1
+		return tx.Put(ctx, todo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+func (r *Reconciler) listAllActions(ctx context.Context) (map[string]*external.Action, error) {
+	actions := map[string]*external.Action{}
+	for offset := 0; ; {
+		page, err := r.sc.ListActions(ctx, &external.ListActionsRequest{Offset: offset, PageSize: listPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list SafetyCulture actions: %w", err)
+		}
+		for _, a := range page.Actions {
+			actions[a.TaskID] = a
+		}
+		offset += len(page.Actions)
+		if len(page.Actions) == 0 || offset >= page.Total {
+			return actions, nil
+		}
+	}
+}
+
+// unsettled are the todos SC can't be trusted on yet: those with an outbox operation that hasn't
+// reached SC, and those whose operation was sent since started, after SC was read
+func (r *Reconciler) unsettled(ctx context.Context, tx store.TodoStore, started time.Time) (map[string]bool, error) {
+	ops, err := tx.PendingOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	queued := r.outbox.ChangedSince(started)
+	for _, op := range ops {
+		for _, id := range outbox.TodoIDs(op) {
+			queued[id] = true
+		}
+	}
+	return queued, nil
+}
+
+// applyAction imports or refreshes the local copy of action, todo is nil if there is none
+func applyAction(ctx context.Context, tx store.TodoStore, todo *pb.Todo, action *external.Action, report *Report) error {
+	switch {
+	case todo == nil || todo.GetDeletedAt() != nil:
+		imported := todoFromAction(action)
+		// a todo that comes back from a tombstone keeps counting up, so old versions can't match again
+		imported.Version = todo.GetVersion() + 1
+		// it stays with its tenant and owner, actions nobody here created go to the default tenant
+		keepLocal(imported, todo)
+		if err := tx.Put(ctx, imported); err != nil {
+			return err
+		}
+		report.Imported++
+		report.counts(imported.GetTenant()).Imported++
+	case todo.GetSyncState() == pb.SyncState_SYNC_STATE_FAILED:
+		if differs(todo, action) {
+			report.Conflicts = append(report.Conflicts, Conflict{ID: todo.GetId(), Reason: "changed in SafetyCulture while a local change failed to sync"})
+		}
+	case differs(todo, action):
+		refreshed := todoFromAction(action)
+		refreshed.CreatedAt = todo.GetCreatedAt()
+		refreshed.Version = todo.GetVersion() + 1
+		keepLocal(refreshed, todo)
+		if err := tx.Put(ctx, refreshed); err != nil {
+			return err
+		}
+		report.Updated++
+		report.counts(refreshed.GetTenant()).Updated++
+	}
+	return nil
+}
+
+// applyRemoved tombstones a todo whose action no longer exists in SC, unless the action may only be hidden from us
+func applyRemoved(ctx context.Context, tx store.TodoStore, todo *pb.Todo, report *Report) error {
+	if todo.GetDeletedAt() != nil {
+		return nil
+	}
+	if todo.GetSyncState() == pb.SyncState_SYNC_STATE_FAILED {
+		report.Conflicts = append(report.Conflicts, Conflict{ID: todo.GetId(), Reason: "deleted in SafetyCulture while a local change failed to sync"})
+		return nil
+	}
+	if todo.GetCreatedWithCallerToken() {
+		// SC is read with the shared token, which may just not be allowed to see the caller's action
+		return nil
+	}
+	todo.DeletedAt = timestamppb.Now()
+	todo.SyncState = pb.SyncState_SYNC_STATE_SYNCED
+	todo.Version++
+	if err := tx.Put(ctx, todo); err != nil {
+		return err
+	}
+	report.Tombstoned++
+	report.counts(todo.GetTenant()).Tombstoned++
+	return nil
+}
+
+// keepLocal copies the fields SC doesn't have, or that only flow from here to SC, from the local todo.
+// Assignees are pushed to SC but never read back, SC users don't map back to our subjects.
+func keepLocal(dst, local *pb.Todo) {
+	dst.Tenant = local.GetTenant()
+	dst.Owner = local.GetOwner()
+	dst.Assignees = local.GetAssignees()
+	dst.SharedWith = local.GetSharedWith()
+	dst.CreatedWithCallerToken = local.GetCreatedWithCallerToken()
+}
+
+func todoFromAction(a *external.Action) *pb.Todo {
+	todo := &pb.Todo{
+		Id:          a.TaskID,
+		Title:       a.Title,
+		Description: a.Description,
+		Completed:   a.Completed(),
+		SyncState:   pb.SyncState_SYNC_STATE_SYNCED,
+	}
+	if !a.CreatedAt.IsZero() {
+		todo.CreatedAt = timestamppb.New(a.CreatedAt)
+	} else {
+		todo.CreatedAt = timestamppb.Now()
+	}
+	return todo
+}
+
+// differs reports whether the fields mirrored in SC disagree
+func differs(todo *pb.Todo, a *external.Action) bool {
+	return todo.GetTitle() != a.Title ||
+		todo.GetDescription() != a.Description ||
+		todo.GetCompleted() != a.Completed()
+}
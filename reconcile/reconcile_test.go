@@ -0,0 +1,269 @@
+package reconcile_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/external/scfake"
+	"github.com/jerryhong21/todo-grpc/outbox"
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/reconcile"
+	"github.com/jerryhong21/todo-grpc/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+const token = "test-token"
+
+// listHook runs during ListActions, to change things while the reconciler is listing SC
+type listHook struct {
+	external.ActionsAPI
+	during func()
+}
+
+func (h *listHook) ListActions(ctx context.Context, req *external.ListActionsRequest) (*external.ListActionsResponse, error) {
+	res, err := h.ActionsAPI.ListActions(ctx, req)
+	if h.during != nil {
+		h.during()
+		h.during = nil
+	}
+	return res, err
+}
+
+type env struct {
+	todos      *store.MemoryStore
+	fake       *scfake.Server
+	sc         *listHook
+	worker     *outbox.Worker
+	reconciler *reconcile.Reconciler
+}
+
+func newEnv(t *testing.T) *env {
+	fake := scfake.New(token)
+	t.Cleanup(fake.Close)
+	sc := &listHook{ActionsAPI: external.NewSCClient(fake.URL, token)}
+	todos := store.NewMemoryStore()
+	worker := outbox.NewWorker(todos, sc)
+	return &env{todos: todos, fake: fake, sc: sc, worker: worker, reconciler: reconcile.NewReconciler(todos, sc, worker)}
+}
+
+// create stores a todo and syncs it to SC, like CreateTodo does
+func (e *env) create(t *testing.T, todo *pb.Todo) {
+	t.Helper()
+	ctx := context.Background()
+	op, err := outbox.NewCreate(&external.CreateActionRequest{TaskID: todo.GetId(), Title: todo.GetTitle(), IdempotencyKey: "create-" + todo.GetId()})
+	if err != nil {
+		t.Fatalf("NewCreate: %v", err)
+	}
+	todo.SyncState = pb.SyncState_SYNC_STATE_PENDING
+	todo.Version = 1
+	err = e.todos.Tx(ctx, func(tx store.TodoStore) error {
+		if err := tx.Put(ctx, todo); err != nil {
+			return err
+		}
+		return tx.Enqueue(ctx, op)
+	})
+	if err != nil {
+		t.Fatalf("storing todo: %v", err)
+	}
+	if _, err := e.worker.SyncTodos(ctx, todo.GetId()); err != nil {
+		t.Fatalf("SyncTodos: %v", err)
+	}
+}
+
+func (e *env) get(t *testing.T, id string) *pb.Todo {
+	t.Helper()
+	todo, err := e.todos.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get %s: %v", id, err)
+	}
+	return todo
+}
+
+func (e *env) reconcile(t *testing.T) *reconcile.Report {
+	t.Helper()
+	report, err := e.reconciler.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	return report
+}
+
+func TestReconcileImports(t *testing.T) {
+	e := newEnv(t)
+	e.fake.PutAction(&external.Action{TaskID: "from-sc", Title: "made in the web app"})
+
+	report := e.reconcile(t)
+	if report.Imported != 1 || report.Tenant("").Imported != 1 {
+		t.Fatalf("report is %+v, want one import into the default tenant", report)
+	}
+	todo := e.get(t, "from-sc")
+	if todo.GetTitle() != "made in the web app" || todo.GetSyncState() != pb.SyncState_SYNC_STATE_SYNCED || todo.GetTenant() != "" {
+		t.Fatalf("imported %v", todo)
+	}
+}
+
+func TestReconcileRefreshes(t *testing.T) {
+	e := newEnv(t)
+	e.create(t, &pb.Todo{Id: "a", Title: "old", Tenant: "team-a", Owner: "alice", Assignees: []string{"bob"}})
+	action, _ := e.fake.Action("a")
+	action.Title = "edited in SC"
+	e.fake.PutAction(action)
+
+	report := e.reconcile(t)
+	if report.Updated != 1 || report.Tenant("team-a").Updated != 1 || report.Tenant("").Updated != 0 {
+		t.Fatalf("report is %+v, want one update counted for team-a only", report)
+	}
+	todo := e.get(t, "a")
+	if todo.GetTitle() != "edited in SC" || todo.GetVersion() != 2 {
+		t.Fatalf("refreshed todo is %v, want the new title at version 2", todo)
+	}
+	if todo.GetTenant() != "team-a" || todo.GetOwner() != "alice" || len(todo.GetAssignees()) != 1 {
+		t.Fatalf("refresh lost the local fields: %v", todo)
+	}
+}
+
+func TestReconcileTombstones(t *testing.T) {
+	e := newEnv(t)
+	e.create(t, &pb.Todo{Id: "a", Title: "a"})
+	e.fake.RemoveAction("a")
+
+	report := e.reconcile(t)
+	if report.Tombstoned != 1 {
+		t.Fatalf("report is %+v, want one tombstone", report)
+	}
+	if todo := e.get(t, "a"); todo.GetDeletedAt() == nil {
+		t.Fatalf("todo %v wasn't tombstoned", todo)
+	}
+}
+
+func TestReconcileKeepsTodosCreatedWithCallerTokens(t *testing.T) {
+	e := newEnv(t)
+	e.create(t, &pb.Todo{Id: "a", Title: "a", CreatedWithCallerToken: true})
+	// the shared account can't see the caller's action, to it the action looks deleted
+	e.fake.RemoveAction("a")
+
+	if report := e.reconcile(t); report.Tombstoned != 0 {
+		t.Fatalf("report is %+v, a todo created with the caller's token was tombstoned", report)
+	}
+	if _, err := e.reconciler.Apply(context.Background(), "a"); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if todo := e.get(t, "a"); todo.GetDeletedAt() != nil {
+		t.Fatalf("todo %v was tombstoned", todo)
+	}
+
+	// the flag outlives refreshes from SC
+	e.fake.PutAction(&external.Action{TaskID: "a", Title: "edited in SC"})
+	e.reconcile(t)
+	if todo := e.get(t, "a"); todo.GetTitle() != "edited in SC" || !todo.GetCreatedWithCallerToken() {
+		t.Fatalf("refreshed todo is %v, want the new title and the flag kept", todo)
+	}
+}
+
+func TestReconcileReportsConflicts(t *testing.T) {
+	e := newEnv(t)
+	e.create(t, &pb.Todo{Id: "a", Title: "a"})
+	ctx := context.Background()
+	todo := e.get(t, "a")
+	todo.SyncState = pb.SyncState_SYNC_STATE_FAILED
+	if err := e.todos.Put(ctx, todo); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	action, _ := e.fake.Action("a")
+	action.Title = "edited in SC"
+	e.fake.PutAction(action)
+
+	report := e.reconcile(t)
+	if len(report.Conflicts) != 1 || report.Conflicts[0].ID != "a" || report.Updated != 0 {
+		t.Fatalf("report is %+v, want a conflict and no update", report)
+	}
+	if got := e.get(t, "a"); got.GetTitle() != "a" {
+		t.Fatalf("conflicting todo was overwritten: %v", got)
+	}
+}
+
+func TestReconcileSkipsQueuedChanges(t *testing.T) {
+	e := newEnv(t)
+	e.create(t, &pb.Todo{Id: "a", Title: "a"})
+	e.fake.RemoveAction("a")
+	// SC is down for the local change, so it stays queued through the reconciliation
+	update, _ := outbox.NewUpdate("a", &external.UpdateActionRequest{Title: proto.String("renamed")})
+	e.fake.InjectFault(scfake.Fault{PathPrefix: "/tasks/v1/actions/a", Status: http.StatusServiceUnavailable, Code: codes.Unavailable})
+	ctx := context.Background()
+	if err := e.todos.Enqueue(ctx, update); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	report := e.reconcile(t)
+	if report.Tombstoned != 0 {
+		t.Fatalf("report is %+v, a todo with a queued change was tombstoned", report)
+	}
+}
+
+func TestReconcileKeepsTodosCreatedWhileListing(t *testing.T) {
+	e := newEnv(t)
+	// created and synced after SC was listed, so it is missing from the listing but not from the store
+	e.sc.during = func() {
+		e.create(t, &pb.Todo{Id: "late", Title: "late"})
+	}
+
+	report := e.reconcile(t)
+	if report.Tombstoned != 0 {
+		t.Fatalf("report is %+v, the todo created during the listing was tombstoned", report)
+	}
+	if todo := e.get(t, "late"); todo.GetDeletedAt() != nil {
+		t.Fatalf("todo %v was tombstoned", todo)
+	}
+
+	// the next run lists it like any other
+	if report := e.reconcile(t); report.Tombstoned != 0 || report.Imported != 0 || report.Updated != 0 {
+		t.Fatalf("the next reconciliation changed %+v", report)
+	}
+}
+
+func TestApply(t *testing.T) {
+	e := newEnv(t)
+	ctx := context.Background()
+	e.create(t, &pb.Todo{Id: "a", Title: "a"})
+	e.fake.RemoveAction("a")
+
+	report, err := e.reconciler.Apply(ctx, "a")
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if report.Tombstoned != 1 {
+		t.Fatalf("report is %+v, want the removed action tombstoned", report)
+	}
+
+	e.fake.PutAction(&external.Action{TaskID: "b", Title: "b"})
+	if _, err := e.reconciler.Apply(ctx, "b"); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if todo := e.get(t, "b"); todo.GetTitle() != "b" {
+		t.Fatalf("Apply imported %v", todo)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	e := newEnv(t)
+	ctx := context.Background()
+	if _, err := e.reconciler.Fetch(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Fetch of an action SC doesn't have: got %v, want ErrNotFound", err)
+	}
+
+	e.fake.PutAction(&external.Action{TaskID: "a", Title: "a"})
+	todo, err := e.reconciler.Fetch(ctx, "a")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if todo.GetVersion() != 1 || todo.GetSyncState() != pb.SyncState_SYNC_STATE_SYNCED {
+		t.Fatalf("Fetch returned %v", todo)
+	}
+	if stored := e.get(t, "a"); stored.GetTitle() != "a" {
+		t.Fatalf("Fetch stored %v", stored)
+	}
+}
@@ -0,0 +1,47 @@
+// Runs the fake SafetyCulture API so the server can be tried out offline:
+//
+//	go run ./scfake -addr localhost:8081
+//	SC_BASE_URL=http://localhost:8081 go run ./server
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/external/scfake"
+	"google.golang.org/grpc/codes"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8081", "address to listen on")
+	token := flag.String("token", "", "bearer token to require, empty accepts any")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	webhookURL := flag.String("webhook-url", "", "post signed action events here, e.g. http://localhost:8082/webhooks/safetyculture")
+	webhookSecret := flag.String("webhook-secret", "", "secret used to sign webhook events, must match the server's SC_WEBHOOK_SECRET")
+	failNext := flag.Int("fail", 0, "fail the next N requests with 503, to try out retries and the circuit breaker")
+	flag.Parse()
+
+	fake := scfake.NewUnstarted(*token)
+	fake.SetLatency(*latency)
+	fake.SetWebhook(*webhookURL, *webhookSecret)
+	if *failNext > 0 {
+		fake.InjectFault(scfake.Fault{
+			Status:  http.StatusServiceUnavailable,
+			Code:    codes.Unavailable,
+			Message: "injected failure",
+			Times:   *failNext,
+		})
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           fake.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	log.Printf("Fake SafetyCulture API is running on http://%s", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
@@ -1,29 +1,55 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"encoding/base64"
+	"errors"
+	"expvar"
+	"flag"
 	"fmt"
-	"io"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
-	"github.com/joho/godotenv"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jerryhong21/todo-grpc/auth"
+	"github.com/jerryhong21/todo-grpc/config"
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/idempotency"
+	"github.com/jerryhong21/todo-grpc/outbox"
+	"github.com/jerryhong21/todo-grpc/policy"
 	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/reconcile"
+	"github.com/jerryhong21/todo-grpc/store"
+	"github.com/jerryhong21/todo-grpc/tenant"
+	"github.com/jerryhong21/todo-grpc/tlsconfig"
+	"github.com/jerryhong21/todo-grpc/validation"
+	"github.com/jerryhong21/todo-grpc/webhook"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
-	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // this is where i implement the functions
 /**
 1. CreateTodo - DONE
 2. GetTodo
-3. PostTodo
+3. UpdateTodo - DONE
 4. BulkDeleteTodo - DONE
-5. ListTodo
+5. ListTodos - DONE
 */
 
 // This is the standard gRPC method signature in Go
@@ -31,236 +57,1079 @@ import (
 
 type server struct {
 	pb.UnimplementedTodoServiceServer
-	todos map[string]*pb.Todo // maps todo Ids to todo
-}
+	todos store.TodoStore
+	sc    external.ActionsAPI // every todo is mirrored as a SafetyCulture action
 
-func NewServer() *server {
-	return &server{
-		todos: make(map[string]*pb.Todo),
-	}
-}
+	// outbox pushes local changes to SC, in the background if SC can't be reached straight away
+	outbox *outbox.Worker
+	// reconciler pulls changes made directly in SC into the store
+	reconciler *reconcile.Reconciler
 
-type CreateTodoPayload struct {
-	TaskID      string `json:"task_id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	// serveLocalWhenOpen lets GetTodo answer from the store while the SC circuit breaker is open
+	serveLocalWhenOpen bool
+	// tokenPolicy decides whose SC token a caller's changes are made with, see scToken
+	tokenPolicy string
+	// quotas caps how many todos each tenant can have
+	quotas tenant.Quotas
+	// userIDs maps callers' subjects to SC user ids, for action assignees
+	userIDs map[string]string
 }
 
-type GetTodoPayload struct {
-	TaskID string `json:"task_id"`
-}
+// scTokenHeader is the metadata a caller sends their own SC token in
+const scTokenHeader = "safetyculture-token"
 
-type BulkDeleteTodoPayload struct {
-	TaskIDs []string `json:"ids"`
+func NewServer(todos store.TodoStore, sc external.ActionsAPI, worker *outbox.Worker, reconciler *reconcile.Reconciler) *server {
+	return &server{
+		todos:      todos,
+		sc:         sc,
+		outbox:     worker,
+		reconciler: reconciler,
+	}
 }
 
-// // Standard struct for SafetyCulture API error response
-// type ScErrorResponse struct {
-// 	Code int `json:"code"`
-// 	Message string `json:"message"`
-// 	Details []any `json:"details"`
-// }
-
 // CreateTodo
 // Returns a pb.Todo object
 // context.Context is a type interaface (inherently a pointer) and therefore does not need a pointer
 
 func (s *server) CreateTodo(ctx context.Context, req *pb.CreateTodoRequest) (*pb.Todo, error) {
+	token, err := s.scToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = external.WithToken(ctx, token)
+
+	// the id is optional, without one the server picks a time ordered UUID.
+	// Ids are unique across tenants, so a tenant choosing one could find out whether another
+	// tenant has it. Only the default tenant can, and every tenant's ids are random to it.
+	id := req.GetId()
+	if id != "" && tenant.FromContext(ctx) != tenant.Default {
+		return nil, status.Error(codes.InvalidArgument, "todos in a tenant get their id from the server, leave id empty")
+	}
+	if id == "" {
+		generated, err := uuid.NewV7()
+		if err != nil {
+			log.Printf("Failed to generate todo id: %v", err)
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+		id = generated.String()
+	} else {
+		id = normaliseID(id)
+	}
 
-	// send a request to SC API to create todo
-	SC_ACTIONS_URL := "https://api.safetyculture.io/tasks/v1/actions"
+	// the todo id also identifies this create in SC if it has to be retried
+	op, err := outbox.NewCreate(&external.CreateActionRequest{
+		TaskID:         id,
+		Title:          req.GetTitle(),
+		Description:    req.GetDescription(),
+		IdempotencyKey: "create-" + id,
+	})
+	if err != nil {
+		log.Printf("Failed to build outbox operation: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
 
-	// Create
-	payloadData := CreateTodoPayload{
-		TaskID:      req.GetId(),
-		Title:       req.GetTitle(),
-		Description: req.GetDescription(),
+	tenantID := tenant.FromContext(ctx)
+	responseTodo := &pb.Todo{
+		Id:                     id,
+		Tenant:                 tenantID,
+		Owner:                  subject(ctx),
+		Title:                  req.GetTitle(),
+		Description:            req.GetDescription(),
+		Completed:              false,
+		CreatedAt:              timestamppb.Now(),
+		SyncState:              pb.SyncState_SYNC_STATE_PENDING,
+		Version:                1,
+		CreatedWithCallerToken: token != "",
 	}
 
-	payloadBytes, err := json.Marshal(payloadData)
+	// Populate the server data, SC is told about it through the outbox
+	err = s.todos.Tx(ctx, func(tx store.TodoStore) error {
+		existing, err := tx.Get(ctx, id)
+		switch {
+		case err == nil && (existing.GetDeletedAt() == nil || existing.GetTenant() != tenantID):
+			// ids are unique across tenants, so another tenant's todo, deleted or not, keeps its id.
+			// Only the default tenant gets here with an id of its own choosing, see above.
+			return status.Errorf(codes.AlreadyExists, "todo %s already exists", id)
+		case err == nil:
+			// reusing the id of a tombstoned todo, keep counting its versions up
+			responseTodo.Version = existing.GetVersion() + 1
+		case !errors.Is(err, store.ErrNotFound):
+			return err
+		}
+		if err := s.checkQuota(ctx, tx, tenantID); err != nil {
+			return err
+		}
+		if err := tx.Put(ctx, responseTodo); err != nil {
+			return err
+		}
+		return s.enqueue(ctx, tx, op, token)
+	})
+	if err != nil {
+		s.outbox.Forget(op.ID, token)
+	}
+	if code := status.Code(err); code == codes.AlreadyExists || code == codes.ResourceExhausted {
+		return nil, err
+	}
 	if err != nil {
-		fmt.Printf("Failed to encode payload: %v", err)
-		return nil, fmt.Errorf("internal server error")
+		log.Printf("Failed to store todo: %v", err)
+		return nil, status.Error(codes.Internal, "failed to store todo")
 	}
 
-	payload := bytes.NewReader(payloadBytes)
+	return s.syncAndGet(ctx, id)
+}
 
-	// Make the HTTP req to SC platform
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", SC_ACTIONS_URL, payload)
+// BulkDeleteTodo deletes the todos locally and in SC using the bulk delete API
+// Returns one result per distinct id, saying whether it was deleted, not found, not allowed, failed or is still queued
+func (s *server) BulkDeleteTodo(ctx context.Context, req *pb.BulkDeleteTodoRequest) (*pb.BulkDeleteTodoResponse, error) {
+	token, err := s.scToken(ctx)
 	if err != nil {
-		fmt.Printf("Failed to create HTTP request: %v", err)
-		return nil, fmt.Errorf("internal server error")
-	}
-	// Add relevant details to the header
-	httpReq.Header.Add("accept", "application/json")
-	httpReq.Header.Add("content-type", "application/json")
-	API_KEY := os.Getenv("SC_API_KEY")
-	httpReq.Header.Add("authorization", "Bearer "+API_KEY)
-
-	// Retrieve response
-	res, _:= http.DefaultClient.Do(httpReq)
-	resBody, err := handleResponse(res)
-	if resBody == nil && err != nil {
-		fmt.Printf("The API returned with an error: %v", err)
 		return nil, err
 	}
+	ctx = external.WithToken(ctx, token)
 
-	fmt.Println("Successfully retrieved API - here is the response from SafetyCulture API")
-	fmt.Println(string(resBody))
+	res := &pb.BulkDeleteTodoResponse{}
 
-	// return the pb.Todo
-	responseTodo := &pb.Todo{
-		Id:          req.GetId(),
-		Title:       req.GetTitle(),
-		Description: req.GetDescription(),
-		Completed:   false,
+	// a repeated id gets a single result
+	var ids []string
+	seen := map[string]bool{}
+	for _, raw := range req.GetIds() {
+		id := normaliseID(raw)
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	versions := map[string]int64{}
+	for raw, version := range req.GetVersions() {
+		versions[normaliseID(raw)] = version
+	}
+	if len(ids) == 0 {
+		return res, nil
 	}
 
-	// Populate the server data
-	s.todos[req.GetId()] = responseTodo
+	// SC silently ignores ids it doesn't have, so look up the ones we don't know to tell them apart
+	notFound := map[string]bool{}
+	forbidden := map[string]bool{}
+	var toDelete []string
+	for _, id := range ids {
+		todo, exists, err := s.deletable(ctx, id)
+		if err != nil {
+			log.Printf("Failed to read todo from store: %v", err)
+			return nil, status.Error(codes.Internal, "failed to read todo from store")
+		}
+		if !exists {
+			notFound[id] = true
+			continue
+		}
+		if todo != nil && !canModify(ctx, todo) {
+			forbidden[id] = true
+			continue
+		}
+		toDelete = append(toDelete, id)
+	}
+
+	// todos stay in the store, marked pending, until SC confirms their action is gone
+	if len(toDelete) > 0 {
+		op, err := outbox.NewDelete(toDelete)
+		if err != nil {
+			log.Printf("Failed to build outbox operation: %v", err)
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+		err = s.todos.Tx(ctx, func(tx store.TodoStore) error {
+			// check every version before changing anything, so a mismatch deletes nothing
+			todos := map[string]*pb.Todo{}
+			mismatched := map[string]int64{}
+			for _, id := range toDelete {
+				todo, err := tx.Get(ctx, id)
+				if errors.Is(err, store.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				if !visible(ctx, todo) || !canModify(ctx, todo) {
+					// another tenant created it or its assignees changed since we looked
+					return status.Errorf(codes.Aborted, "todo %s changed while it was being deleted, retry", id)
+				}
+				if want, ok := versions[id]; ok && want != todo.GetVersion() {
+					mismatched[id] = todo.GetVersion()
+				}
+				todos[id] = todo
+			}
+			if len(mismatched) > 0 {
+				return versionMismatch(mismatched)
+			}
+
+			for _, todo := range todos {
+				todo.SyncState = pb.SyncState_SYNC_STATE_PENDING
+				if err := tx.Put(ctx, todo); err != nil {
+					return err
+				}
+			}
+			return s.enqueue(ctx, tx, op, token)
+		})
+		if err != nil {
+			s.outbox.Forget(op.ID, token)
+		}
+		if status.Code(err) == codes.Aborted {
+			return nil, err
+		}
+		if err != nil {
+			log.Printf("Failed to queue todo deletion: %v", err)
+			return nil, status.Error(codes.Internal, "failed to delete todos from store")
+		}
+	}
+
+	scErrs := s.syncNow(ctx, toDelete...)
+
+	// the deletes are committed, so the results are read even if the deadline has passed,
+	// an idempotent retry then gets them back rather than a fresh run
+	readCtx := context.WithoutCancel(ctx)
+	queued, err := s.queuedTodoIDs(readCtx)
+	if err != nil {
+		log.Printf("Failed to read outbox: %v", err)
+		return nil, status.Error(codes.Internal, "failed to read todo deletion results")
+	}
 
-	return responseTodo, nil
+	for _, id := range ids {
+		result := &pb.DeleteResult{Id: id}
+		res.Results = append(res.Results, result)
+
+		scErr := scErrs[id]
+		if scErr != nil {
+			result.ErrorMessage = status.Convert(scErr).Message()
+		}
+		switch {
+		case notFound[id]:
+			result.Status = pb.DeleteStatus_DELETE_STATUS_NOT_FOUND
+		case forbidden[id]:
+			result.Status = pb.DeleteStatus_DELETE_STATUS_PERMISSION_DENIED
+			result.ErrorMessage = "only the todo's owner and assignees can delete it"
+		case queued[id]:
+			result.Status = pb.DeleteStatus_DELETE_STATUS_PENDING
+		case status.Code(scErr) == codes.PermissionDenied:
+			result.Status = pb.DeleteStatus_DELETE_STATUS_PERMISSION_DENIED
+		case scErr != nil:
+			result.Status = pb.DeleteStatus_DELETE_STATUS_FAILED
+		default:
+			// the background worker may have got to it first, in which case only the store knows how it went
+			todo, err := s.todos.Get(readCtx, id)
+			if err == nil && todo.GetSyncState() == pb.SyncState_SYNC_STATE_FAILED {
+				result.Status = pb.DeleteStatus_DELETE_STATUS_FAILED
+				result.ErrorMessage = "SafetyCulture rejected the delete"
+			} else {
+				result.Status = pb.DeleteStatus_DELETE_STATUS_DELETED
+			}
+		}
+	}
+	return res, nil
 }
 
-// Delete a todo item using bulk delete API
-// Returns nothing
-func (s *server) BulkDeleteTodo(ctx context.Context, req *pb.BulkDeleteTodoRequest) (*emptypb.Empty, error) {
+// deletable reports whether a delete of id has anything to remove, locally or in SC, along with
+// the local todo if there is one. Todos the caller can't see are never deletable.
+// If SC can't be asked, the delete is attempted anyway.
+func (s *server) deletable(ctx context.Context, id string) (*pb.Todo, bool, error) {
+	todo, err := s.todos.Get(ctx, id)
+	if err == nil {
+		return todo, visible(ctx, todo) && todo.GetDeletedAt() == nil, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, false, err
+	}
+	// actions only in SC belong to the default tenant once imported
+	if tenant.FromContext(ctx) != tenant.Default {
+		return nil, false, nil
+	}
 
-	// concatenates delete action prefix and actionId
-	ids := req.GetIds()
-	SC_DELETE_TODO_URL := "https://api.safetyculture.io/tasks/v1/actions/delete"
+	_, err = s.sc.GetAction(ctx, id)
+	return nil, status.Code(err) != codes.NotFound, nil
+}
 
-	// Create a payload struct, JSONIFY it
-	payloadData := BulkDeleteTodoPayload{
-		TaskIDs: ids,
+// queuedTodoIDs are the todos with an outbox operation still waiting for SC
+func (s *server) queuedTodoIDs(ctx context.Context) (map[string]bool, error) {
+	ops, err := s.todos.PendingOperations(ctx)
+	if err != nil {
+		return nil, err
 	}
-	payloadBytes, err := json.Marshal(payloadData)
+	queued := map[string]bool{}
+	for _, op := range ops {
+		for _, id := range outbox.TodoIDs(op) {
+			queued[id] = true
+		}
+	}
+	return queued, nil
+}
+
+func (s *server) GetTodo(ctx context.Context, req *pb.GetTodoRequest) (*pb.Todo, error) {
+	token, err := s.scToken(ctx)
 	if err != nil {
-		fmt.Printf("Failed to encode payload: %v", err)
-		return nil, fmt.Errorf("internal server error")
+		return nil, err
 	}
-	payload := bytes.NewReader(payloadBytes)
+	ctx = external.WithToken(ctx, token)
+	id := normaliseID(req.GetId())
 
-	// The below works for single deletion payload, the above works for bulk deletion
-	// payload := strings.NewReader(fmt.Sprintf(`{"ids":["%s"]}`, id))
+	todo, err := s.todos.Get(ctx, id)
+	if err == nil && !visible(ctx, todo) {
+		return nil, status.Errorf(codes.NotFound, "todo %s not found", id)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		if tenant.FromContext(ctx) != tenant.Default {
+			return nil, status.Errorf(codes.NotFound, "todo %s not found", id)
+		}
+		// it may have been created in the SC web app, import it rather than waiting for the reconciler
+		todo, err = s.reconciler.Fetch(ctx, id)
+		// Fetch returns the local todo if one was created meanwhile, which may not be the caller's
+		if errors.Is(err, store.ErrNotFound) || err == nil && !visible(ctx, todo) {
+			return nil, status.Errorf(codes.NotFound, "todo %s not found", id)
+		}
+		if err != nil {
+			log.Printf("The API returned with an error: %v", err)
+			return nil, err
+		}
+		return todo, nil
+	}
+	if err != nil {
+		log.Printf("Failed to read todo from store: %v", err)
+		return nil, status.Error(codes.Internal, "failed to read todo from store")
+	}
+	if todo.GetDeletedAt() != nil {
+		return nil, status.Errorf(codes.NotFound, "todo %s was deleted in SafetyCulture", id)
+	}
 
-	deleteReq, _ := http.NewRequest("POST", SC_DELETE_TODO_URL, payload)
+	// SC won't know about changes still in the outbox, so only check todos it should have
+	switch todo.GetSyncState() {
+	case pb.SyncState_SYNC_STATE_PENDING, pb.SyncState_SYNC_STATE_FAILED:
+		return todo, nil
+	}
 
-	deleteReq.Header.Add("accept", "application/json")
-	deleteReq.Header.Add("content-type", "application/json")
-	deleteReq.Header.Add("authorization", "Bearer "+os.Getenv("SC_API_KEY"))
+	if _, err := s.sc.GetAction(ctx, id); err != nil {
+		log.Printf("The API returned with an error: %v", err)
+		if !s.serveLocalWhenOpen || !errors.Is(err, external.ErrCircuitOpen) {
+			return nil, err
+		}
+		log.Printf("Serving todo %s from the local store while SafetyCulture is unavailable", id)
+	}
 
-	res, _ := http.DefaultClient.Do(deleteReq)
+	return todo, nil
+}
 
-	body, err := handleResponse(res)
-	if body == nil && err != nil {
-		fmt.Println("The API returned with an error: %w", err)
+// UpdateTodo applies a partial update to a todo
+// Only the fields named in update_mask are changed, an empty mask updates every mutable field
+func (s *server) UpdateTodo(ctx context.Context, req *pb.UpdateTodoRequest) (*pb.Todo, error) {
+	token, err := s.scToken(ctx)
+	if err != nil {
 		return nil, err
 	}
+	ctx = external.WithToken(ctx, token)
+	id := normaliseID(req.GetId())
 
-	// if body is not nil, but empty - this signifies correct
-	if len(body) == 0 {
-		// remove the todo from our body
-		for _, id := range ids {
-			titleRemoved := s.todos[id]
-			delete(s.todos, id)
-			fmt.Printf("Successfully deleted %v\n", titleRemoved)
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = []string{"title", "description", "completed"}
+	}
+	for i, path := range paths {
+		switch path {
+		case "title":
+			// the proto rules allow an empty title so it can be left out of the mask
+			if req.GetTitle() == "" {
+				return nil, validation.InvalidArgument(validation.Violation("title", "title must not be empty"))
+			}
+		case "description", "completed":
+		default:
+			return nil, validation.InvalidArgument(validation.Violation(fmt.Sprintf("update_mask.paths[%d]", i), "unsupported update_mask path %q", path))
 		}
-		return &emptypb.Empty{}, nil
 	}
 
-	// TODO: Experiment and see if ids contain partially valid ids, then does the API remove the valid ones and return error?
-	// If so, then we need to update the behaviour of our function such that the valid IDs are removed
+	// the read, version check and write happen in one transaction so concurrent updates can't overwrite each other
+	var op *store.Operation
+	err = s.todos.Tx(ctx, func(tx store.TodoStore) error {
+		updated, err := tx.Get(ctx, id)
+		if errors.Is(err, store.ErrNotFound) || updated.GetDeletedAt() != nil || (err == nil && !visible(ctx, updated)) {
+			return status.Errorf(codes.NotFound, "todo %s not found", id)
+		}
+		if err != nil {
+			return err
+		}
+		if !canModify(ctx, updated) {
+			return status.Errorf(codes.PermissionDenied, "only the owner and assignees of todo %s can change it", id)
+		}
+		if req.GetVersion() != 0 && req.GetVersion() != updated.GetVersion() {
+			return versionMismatch(map[string]int64{id: updated.GetVersion()})
+		}
+
+		actionUpdate := &external.UpdateActionRequest{}
+		for _, path := range paths {
+			switch path {
+			case "title":
+				updated.Title = req.GetTitle()
+				actionUpdate.Title = proto.String(req.GetTitle())
+			case "description":
+				updated.Description = req.GetDescription()
+				actionUpdate.Description = proto.String(req.GetDescription())
+			case "completed":
+				updated.Completed = req.GetCompleted()
+				statusID := external.StatusToDo
+				if req.GetCompleted() {
+					statusID = external.StatusComplete
+				}
+				actionUpdate.StatusID = proto.String(statusID)
+			}
+		}
+		updated.SyncState = pb.SyncState_SYNC_STATE_PENDING
+		updated.Version++
+
+		op, err = outbox.NewUpdate(id, actionUpdate)
+		if err != nil {
+			return err
+		}
+		if err := tx.Put(ctx, updated); err != nil {
+			return err
+		}
+		return s.enqueue(ctx, tx, op, token)
+	})
+	if err != nil && op != nil {
+		s.outbox.Forget(op.ID, token)
+	}
+	if err != nil {
+		// NotFound and Aborted from inside the transaction go back to the client as they are
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		log.Printf("Failed to store todo: %v", err)
+		return nil, status.Error(codes.Internal, "failed to store todo")
+	}
 
-	return nil, fmt.Errorf("error: Response from SC API:\n %v", string(body))
+	return s.syncAndGet(ctx, id)
 }
 
-func (s *server) GetTodo(ctx context.Context, req *pb.GetTodoRequest) (*pb.Todo, error) {
-	id := req.GetId()
-	SC_GET_TODO_URL := "https://api.safetyculture.io/tasks/v1/actions/" + id
+// AssignTodo replaces the todo's assignees, who are mirrored as the assignees of its SC action
+func (s *server) AssignTodo(ctx context.Context, req *pb.AssignTodoRequest) (*pb.Todo, error) {
+	token, err := s.scToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = external.WithToken(ctx, token)
+	id := normaliseID(req.GetId())
+	assignees := distinct(req.GetAssignees())
 
-	getReq, _ := http.NewRequest("GET", SC_GET_TODO_URL, nil)
-	getReq.Header.Add("accept", "application/json")
-	API_KEY := os.Getenv("SC_API_KEY")
-	getReq.Header.Add("authorization", "Bearer "+API_KEY)
+	var op *store.Operation
+	err = s.todos.Tx(ctx, func(tx store.TodoStore) error {
+		todo, err := ownedTodo(ctx, tx, id, req.GetVersion(), "its assignees")
+		if err != nil {
+			return err
+		}
+		todo.Assignees = assignees
+		todo.SyncState = pb.SyncState_SYNC_STATE_PENDING
+		todo.Version++
 
-	res, _ := http.DefaultClient.Do(getReq)
-	resBody, err := handleResponse(res)
-	if resBody == nil && err != nil {
-		fmt.Println("The API returned with an error: %w", err)
+		collaborators := external.Assignees(s.scUserIDs(assignees))
+		op, err = outbox.NewUpdate(id, &external.UpdateActionRequest{Assignees: &collaborators})
+		if err != nil {
+			return err
+		}
+		if err := tx.Put(ctx, todo); err != nil {
+			return err
+		}
+		return s.enqueue(ctx, tx, op, token)
+	})
+	if err != nil && op != nil {
+		s.outbox.Forget(op.ID, token)
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		log.Printf("Failed to store todo: %v", err)
+		return nil, status.Error(codes.Internal, "failed to store todo")
+	}
+
+	return s.syncAndGet(ctx, id)
+}
+
+// ShareTodo replaces who can read the todo. SC has no notion of it, so nothing is synced.
+func (s *server) ShareTodo(ctx context.Context, req *pb.ShareTodoRequest) (*pb.Todo, error) {
+	id := normaliseID(req.GetId())
+
+	var shared *pb.Todo
+	err := s.todos.Tx(ctx, func(tx store.TodoStore) error {
+		todo, err := ownedTodo(ctx, tx, id, req.GetVersion(), "who it is shared with")
+		if err != nil {
+			return err
+		}
+		todo.SharedWith = distinct(req.GetSharedWith())
+		todo.Version++
+		shared = todo
+		return tx.Put(ctx, todo)
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		log.Printf("Failed to store todo: %v", err)
+		return nil, status.Error(codes.Internal, "failed to store todo")
+	}
+	return shared, nil
+}
+
+// ownedTodo reads todo id for a change only its owner can make, what names the change in the error
+func ownedTodo(ctx context.Context, tx store.TodoStore, id string, version int64, what string) (*pb.Todo, error) {
+	todo, err := tx.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && (todo.GetDeletedAt() != nil || !visible(ctx, todo))) {
+		return nil, status.Errorf(codes.NotFound, "todo %s not found", id)
+	}
+	if err != nil {
 		return nil, err
 	}
+	if !isOwner(ctx, todo) {
+		return nil, status.Errorf(codes.PermissionDenied, "only the owner of todo %s can change %s", id, what)
+	}
+	if version != 0 && version != todo.GetVersion() {
+		return nil, versionMismatch(map[string]int64{id: todo.GetVersion()})
+	}
+	return todo, nil
+}
+
+// subject is the authenticated caller, empty when the server doesn't authenticate callers
+func subject(ctx context.Context) string {
+	if id, ok := auth.FromContext(ctx); ok {
+		return id.Subject
+	}
+	return ""
+}
+
+// visible reports whether the caller can see todo. It has to be in their tenant, and once it has
+// an owner only the owner, assignees and who it is shared with can see it. Todos without an owner,
+// and servers that don't authenticate callers, leave it at the tenant.
+func visible(ctx context.Context, todo *pb.Todo) bool {
+	if todo.GetTenant() != tenant.FromContext(ctx) {
+		return false
+	}
+	return canModify(ctx, todo) || slices.Contains(todo.GetSharedWith(), subject(ctx))
+}
+
+// canModify reports whether the caller can change a todo they can see, only its owner and assignees can
+func canModify(ctx context.Context, todo *pb.Todo) bool {
+	return isOwner(ctx, todo) || slices.Contains(todo.GetAssignees(), subject(ctx))
+}
+
+// isOwner reports whether the caller can decide who a todo is assigned and shared to
+func isOwner(ctx context.Context, todo *pb.Todo) bool {
+	caller := subject(ctx)
+	return caller == "" || todo.GetOwner() == "" || todo.GetOwner() == caller
+}
+
+// distinct drops repeated subjects, keeping the first of each
+func distinct(subjects []string) []string {
+	out := []string{}
+	for _, subject := range subjects {
+		if !slices.Contains(out, subject) {
+			out = append(out, subject)
+		}
+	}
+	return out
+}
 
-	// else, return the response body
-	return s.todos[id], nil
+// scUserIDs maps subjects to SC user ids, subjects without a mapping are taken to be SC user ids already
+func (s *server) scUserIDs(subjects []string) []string {
+	ids := make([]string, 0, len(subjects))
+	for _, subject := range subjects {
+		if id, ok := s.userIDs[subject]; ok {
+			subject = id
+		}
+		ids = append(ids, subject)
+	}
+	return ids
 }
 
-// func (s *server) UpdateTodo(ctx context.Context, req *pb.UpdateTodoRequest) (*pb.Todo, error) {
-// 	id := req.GetId()
+// scToken picks the SC token the caller's requests to SC are made with, following the token policy.
+// An empty token means the server's shared one. The token is never logged.
+func (s *server) scToken(ctx context.Context) (string, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(scTokenHeader); len(values) > 0 {
+			token = strings.TrimSpace(values[0])
+		}
+	}
 
-// }
+	switch s.tokenPolicy {
+	case config.TokenPassthrough:
+		return token, nil
+	case config.TokenRequired:
+		if token == "" {
+			return "", status.Errorf(codes.Unauthenticated, "this server makes changes in SafetyCulture as you, send your SafetyCulture API token in %s metadata", scTokenHeader)
+		}
+		return token, nil
+	default:
+		// shared, everything is done with the server's own token
+		return "", nil
+	}
+}
 
-// function to handle response
-// only checks for whether there are any errors
-func handleResponse(res *http.Response) ([]byte, error) {
-	defer res.Body.Close()
+// enqueue queues op in tx to be made with the caller's SC token. The token is handed to the outbox
+// before tx commits, otherwise a drain could apply op with the shared token first.
+// Callers Forget the token when tx fails.
+func (s *server) enqueue(ctx context.Context, tx store.TodoStore, op *store.Operation, token string) error {
+	if err := tx.Enqueue(ctx, op); err != nil {
+		return err
+	}
+	s.outbox.Delegate(op.ID, token)
+	return nil
+}
 
-	body, err := io.ReadAll(res.Body)
+// checkQuota returns RESOURCE_EXHAUSTED when tenantID already has as many todos as its quota allows.
+// Deleted todos don't count.
+func (s *server) checkQuota(ctx context.Context, tx store.TodoStore, tenantID string) error {
+	limit := s.quotas.Limit(tenantID)
+	if limit <= 0 {
+		return nil
+	}
+	todos, err := tx.List(ctx)
 	if err != nil {
-		fmt.Printf("Failed to read response body: %v", err)
-		return nil, fmt.Errorf("failed to process response from SafetyCulture API")
+		return err
+	}
+	count := 0
+	for _, todo := range todos {
+		if todo.GetTenant() == tenantID && todo.GetDeletedAt() == nil {
+			count++
+		}
+	}
+	if count < limit {
+		return nil
+	}
+
+	quotaSubject := "tenant:" + tenantID
+	if tenantID == tenant.Default {
+		quotaSubject = "tenant:default"
+	}
+	st := status.Newf(codes.ResourceExhausted, "your tenant already has %d todos, the most it can have, delete some first", limit)
+	if withDetails, err := st.WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     quotaSubject,
+			Description: fmt.Sprintf("at most %d todos", limit),
+		}},
+	}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// normaliseID returns id the way the server and SC store it. The validation interceptor
+// has already made sure it is a UUID, which may still arrive in upper case.
+func normaliseID(id string) string {
+	return strings.ToLower(id)
+}
+
+// versionMismatch is the ABORTED error for todos that changed since the client read them,
+// current maps each of them to the version it is at now
+func versionMismatch(current map[string]int64) error {
+	ids := make([]string, 0, len(current))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	failure := &errdetails.PreconditionFailure{}
+	for _, id := range ids {
+		failure.Violations = append(failure.Violations, &errdetails.PreconditionFailure_Violation{
+			Type:        "VERSION",
+			Subject:     id,
+			Description: fmt.Sprintf("todo %s is at version %d", id, current[id]),
+		})
+	}
+
+	st := status.Newf(codes.Aborted, "todo %s was changed by someone else, read it again and retry", strings.Join(ids, ", "))
+	if withDetails, err := st.WithDetails(failure); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// syncNow tries to push the outbox operations for ids to SC before the handler replies.
+// It stops short of the request deadline so the reply still makes it back, anything left over
+// is handed to the background worker. It returns the SC error for each todo that was sent, nil on success.
+func (s *server) syncNow(ctx context.Context, ids ...string) map[string]error {
+	syncCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		syncCtx, cancel = context.WithDeadline(ctx, time.Now().Add(time.Until(deadline)*4/5))
+		defer cancel()
+	}
+
+	results, err := s.outbox.SyncTodos(syncCtx, ids...)
+	if err != nil {
+		log.Printf("Leaving todos %v in the outbox: %v", ids, err)
+	}
+	s.outbox.Notify()
+	return results
+}
+
+// syncAndGet syncs the todo and returns it with its resulting sync_state
+func (s *server) syncAndGet(ctx context.Context, id string) (*pb.Todo, error) {
+	s.syncNow(ctx, id)
+
+	// the change is committed, so it is read back even if the deadline has passed. Otherwise an
+	// idempotent retry would run the handler again, and CreateTodo would make a second todo.
+	todo, err := s.todos.Get(context.WithoutCancel(ctx), id)
+	if err != nil {
+		log.Printf("Failed to read todo from store: %v", err)
+		return nil, status.Error(codes.Internal, "failed to read todo from store")
+	}
+	return todo, nil
+}
+
+// SyncNow runs a reconciliation with SafetyCulture and reports what changed
+func (s *server) SyncNow(ctx context.Context, req *pb.SyncNowRequest) (*pb.SyncNowResponse, error) {
+	report, err := s.reconciler.Reconcile(ctx)
+	if err != nil {
+		log.Printf("Reconciliation failed: %v", err)
+		// keep SC's status code (e.g. Unavailable) when that's what failed
+		if st, ok := status.FromError(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Error(codes.Internal, "reconciliation with SafetyCulture failed")
+	}
+
+	// the reconciliation covers every tenant, but callers only hear about their own
+	counts := report.Tenant(tenant.FromContext(ctx))
+	res := &pb.SyncNowResponse{
+		Imported:   int32(counts.Imported),
+		Updated:    int32(counts.Updated),
+		Tombstoned: int32(counts.Tombstoned),
+		FinishedAt: timestamppb.New(report.FinishedAt),
+	}
+	for _, c := range report.Conflicts {
+		todo, err := s.todos.Get(ctx, c.ID)
+		if err != nil || !visible(ctx, todo) {
+			continue
+		}
+		res.Conflicts = append(res.Conflicts, &pb.SyncConflict{Id: c.ID, Reason: c.Reason})
+	}
+	return res, nil
+}
+
+const (
+	defaultListPageSize = 50
+	maxListPageSize     = 1000
+)
+
+// ListTodos streams the todos matching the request filters, oldest first
+// If more todos remain after this page, the token for the next page is sent in the "next-page-token" trailer
+func (s *server) ListTodos(req *pb.ListTodosRequest, stream grpc.ServerStreamingServer[pb.Todo]) error {
+	// negative sizes never get past the validation interceptor
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize <= 0:
+		pageSize = defaultListPageSize
+	case pageSize > maxListPageSize:
+		pageSize = maxListPageSize
+	}
+
+	var after *pb.Todo
+	if token := req.GetPageToken(); token != "" {
+		cursor, err := decodePageToken(token)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		after = cursor
+	}
+
+	titleContains := strings.ToLower(req.GetTitleContains())
+	todos, err := s.todos.List(stream.Context())
+	if err != nil {
+		log.Printf("Failed to list todos from store: %v", err)
+		return status.Error(codes.Internal, "failed to list todos")
+	}
+
+	matches := []*pb.Todo{}
+	for _, todo := range todos {
+		if !visible(stream.Context(), todo) || todo.GetDeletedAt() != nil {
+			continue
+		}
+		if req.Completed != nil && todo.GetCompleted() != req.GetCompleted() {
+			continue
+		}
+		if titleContains != "" && !strings.Contains(strings.ToLower(todo.GetTitle()), titleContains) {
+			continue
+		}
+		if req.GetCreatedAfter() != nil && !todo.GetCreatedAt().AsTime().After(req.GetCreatedAfter().AsTime()) {
+			continue
+		}
+		if after != nil && !todoLess(after, todo) {
+			continue
+		}
+		matches = append(matches, todo)
+	}
+
+	// the store returns todos in no particular order, so sort to give pages a stable order
+	sort.Slice(matches, func(i, j int) bool {
+		return todoLess(matches[i], matches[j])
+	})
+
+	if len(matches) > pageSize {
+		matches = matches[:pageSize]
+		stream.SetTrailer(metadata.Pairs("next-page-token", encodePageToken(matches[pageSize-1])))
+	}
+
+	for _, todo := range matches {
+		if err := stream.Send(todo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// todoLess orders todos by creation time, breaking ties on the id
+func todoLess(a, b *pb.Todo) bool {
+	at, bt := a.GetCreatedAt().AsTime(), b.GetCreatedAt().AsTime()
+	if !at.Equal(bt) {
+		return at.Before(bt)
 	}
+	return a.GetId() < b.GetId()
+}
 
-	fmt.Println("Body: ")
-	fmt.Println(string(body))
+// A page token is the position of the last todo sent, "<created_at unix nanos>/<id>", base64 encoded
+func encodePageToken(last *pb.Todo) string {
+	raw := fmt.Sprintf("%d/%s", last.GetCreatedAt().AsTime().UnixNano(), last.GetId())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
 
-	// decode the response into a map of keys of type string, which maps to values of ANY kind
-	var result map[string]any
-	err = json.Unmarshal(body, &result)
+func decodePageToken(token string) (*pb.Todo, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, err
+	}
+	nanos, id, found := strings.Cut(string(raw), "/")
+	if !found {
+		return nil, fmt.Errorf("malformed page token")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, err
 	}
+	return &pb.Todo{Id: id, CreatedAt: timestamppb.New(time.Unix(0, n))}, nil
+}
 
-	// if the field "code" exists, then error
-	_, exists := result["code"]
-	if !exists {
-		fmt.Println("Normal response")
-		return body, nil
+// serveDebug exposes expvar diagnostics (including the SC rate limiter) on /debug/vars when addr is set
+func serveDebug(addr string) {
+	if addr == "" {
+		return
 	}
+	go func() {
+		log.Printf("Debug endpoint is running on %s", addr)
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			log.Printf("Debug endpoint stopped: %v", err)
+		}
+	}()
+}
 
-	// Else, print the error
-	return nil, fmt.Errorf("%s", string(body))
-	/*
+// serveWebhooks accepts SC webhook events signed with secret on addr, when addr is set
+func serveWebhooks(addr, secret string, reconciler *reconcile.Reconciler) {
+	if addr == "" {
+		return
+	}
 
-		IF WE WANT OT HANDLE THE ERROR REPSONSE:
+	mux := http.NewServeMux()
+	mux.Handle("/webhooks/safetyculture", webhook.NewHandler(secret, reconciler))
+	webhookServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Printf("Webhook receiver is running on %s", addr)
+		if err := webhookServer.ListenAndServe(); err != nil {
+			log.Printf("Webhook receiver stopped: %v", err)
+		}
+	}()
+}
 
-		errRes := ScErrorResponse{}
+// serverCredentials serves TLS when a certificate is configured, reloading it when its files change,
+// and plaintext otherwise
+func serverCredentials(cfg config.ServerTLS) credentials.TransportCredentials {
+	if cfg.CertFile == "" {
+		return insecure.NewCredentials()
+	}
+	reloader, err := tlsconfig.NewReloader(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile)
+	if err != nil {
+		log.Fatalf("Failed to set up TLS: %v", err)
+	}
+	go func() {
+		if err := reloader.Run(context.Background()); err != nil {
+			log.Printf("TLS certificates won't be reloaded: %v", err)
+		}
+	}()
+	if cfg.ClientCAFile != "" {
+		log.Printf("Serving mutual TLS, client certificates must be signed by %s", cfg.ClientCAFile)
+	} else {
+		log.Printf("Serving TLS with %s", cfg.CertFile)
+	}
+	return credentials.NewTLS(reloader.Config())
+}
+
+// setupLogging sends everything logged through the log package to a slog handler in the configured format
+func setupLogging(cfg config.Log) {
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if cfg.Level == "debug" {
+		opts.Level = slog.LevelDebug
+	}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// logUnary logs every unary RPC at debug level
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	slog.DebugContext(ctx, "rpc", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start))
+	return res, err
+}
 
-		// decode the json string contained in the "mesage field of the results map"
-		// breakdown:
-		// result["message"] is of type "any" - which means we need to make a type assertion: .(string)
-		// []byte(...) converts the string value into a byte slice since json.Unmarshal requires a byte slice
-		// finally, json.Unmarshal decodes JSON data, into a go strct
-		err = json.Unmarshal([]byte(result["message"].(string)), &errRes)
-	*/
+// logStream logs every streaming RPC at debug level
+func logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	slog.DebugContext(ss.Context(), "rpc", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start))
+	return err
+}
+
+// interceptors returns the chains every RPC goes through, authenticator and engine are nil when
+// authentication or authorization is off
+func interceptors(authenticator *auth.Authenticator, engine *policy.Engine, idempotencyKeys *idempotency.Interceptor) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	// callers are identified first, and requests are validated before an idempotency key can be recorded for them
+	unary := []grpc.UnaryServerInterceptor{logUnary}
+	stream := []grpc.StreamServerInterceptor{logStream}
+	if authenticator != nil {
+		unary = append(unary, authenticator.Unary)
+		stream = append(stream, authenticator.Stream)
+	}
+	// the tenant comes from the caller's credentials when there are any
+	unary = append(unary, tenant.UnaryInterceptor)
+	stream = append(stream, tenant.StreamInterceptor)
+	// callers are only told a request is invalid once they are allowed to make it
+	if engine != nil {
+		unary = append(unary, engine.Unary)
+		stream = append(stream, engine.Stream)
+	}
+	unary = append(unary, validation.UnaryInterceptor, idempotencyKeys.Unary)
+	stream = append(stream, validation.StreamInterceptor)
+	return unary, stream
 }
 
 // Main server
 func main() {
+	cfg, err := config.LoadServer(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.PrintConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			log.Fatalf("Failed to print config: %v", err)
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config:\n%v", err)
+	}
+	setupLogging(cfg.Log)
 
-	lis, err := net.Listen("tcp", ":50051")
+	lis, err := net.Listen("tcp", cfg.ListenAddr)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	err = godotenv.Load("../.env")
-    if err != nil {
-        log.Fatalf("Error loading .env file: %v", err)
-    }
+	// todos are kept in memory unless a database file is configured
+	memoryStore := store.NewMemoryStore()
+	var todos store.TodoStore = memoryStore
+	var idempotencyRecords store.IdempotencyStore = memoryStore
+	if cfg.Store.Backend == config.StoreSQLite {
+		sqliteStore, err := store.NewSQLiteStore(cfg.Store.Path)
+		if err != nil {
+			log.Fatalf("Failed to open todo database: %v", err)
+		}
+		defer sqliteStore.Close()
+		todos = sqliteStore
+		idempotencyRecords = sqliteStore
+		log.Printf("Storing todos in %s", cfg.Store.Path)
+	}
+
+	idempotencyKeys := idempotency.NewInterceptor(idempotencyRecords)
+	idempotencyKeys.TTL = cfg.IdempotencyTTL.Duration
+	go idempotencyKeys.Run(context.Background())
+
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled() {
+		var err error
+		authenticator, err = auth.New(auth.Options{
+			APIKeysFile:     cfg.Auth.APIKeysFile,
+			HS256SecretFile: cfg.Auth.HS256SecretFile,
+			JWKSFile:        cfg.Auth.JWKSFile,
+			Issuer:          cfg.Auth.Issuer,
+			Audience:        cfg.Auth.Audience,
+		})
+		if err != nil {
+			log.Fatalf("Failed to set up authentication: %v", err)
+		}
+	} else {
+		log.Printf("Authentication is off, anyone who can reach %s can use the API", cfg.ListenAddr)
+	}
+	var engine *policy.Engine
+	if cfg.Auth.PolicyFile != "" {
+		var err error
+		engine, err = policy.Load(cfg.Auth.PolicyFile)
+		if err != nil {
+			log.Fatalf("Failed to load authorization policy: %v", err)
+		}
+		if missing := engine.Unmapped(&pb.TodoService_ServiceDesc); len(missing) > 0 {
+			log.Printf("Nobody can call %s, %s doesn't name a permission for them", strings.Join(missing, ", "), cfg.Auth.PolicyFile)
+		}
+	}
+	unary, stream := interceptors(authenticator, engine, idempotencyKeys)
+
+	grpcServer := grpc.NewServer(
+		grpc.Creds(serverCredentials(cfg.TLS)),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	sc := external.NewSCClient(cfg.SafetyCulture.BaseURL, cfg.SafetyCulture.APIKey.Reveal())
+	sc.HTTPClient.Timeout = cfg.SafetyCulture.Timeout.Duration
+	sc.RateLimiter.SetLimit(cfg.SafetyCulture.RateLimit)
+	sc.RateLimiter.SetBurst(cfg.SafetyCulture.RateBurst)
+	sc.Breaker.FailureThreshold = cfg.SafetyCulture.BreakerFailures
+	sc.Breaker.CoolDown = cfg.SafetyCulture.BreakerCoolDown.Duration
+	expvar.Publish("sc_rate_limiter", expvar.Func(func() any {
+		return sc.RateLimiter.State()
+	}))
+	expvar.Publish("sc_circuit_breaker", expvar.Func(func() any {
+		return sc.Breaker.State()
+	}))
+	serveDebug(cfg.DebugAddr)
+
+	worker := outbox.NewWorker(todos, sc)
+	if cfg.SafetyCulture.Timeout.Duration > 0 {
+		worker.ApplyTimeout = cfg.SafetyCulture.Timeout.Duration
+	}
+	worker.RequireToken = cfg.SafetyCulture.TokenPolicy == config.TokenRequired
+	go worker.Run(context.Background())
+
+	reconciler := reconcile.NewReconciler(todos, sc, worker)
+	reconciler.Interval = cfg.ReconcileInterval.Duration
+	go reconciler.Run(context.Background())
+	serveWebhooks(cfg.WebhookAddr, cfg.SafetyCulture.WebhookSecret.Reveal(), reconciler)
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterTodoServiceServer(grpcServer, NewServer())
-	log.Println("gRPC server is running on port :50051")
+	todoServer := NewServer(todos, sc, worker, reconciler)
+	todoServer.serveLocalWhenOpen = cfg.SafetyCulture.ServeLocalWhenOpen
+	todoServer.tokenPolicy = cfg.SafetyCulture.TokenPolicy
+	todoServer.quotas = tenant.Quotas{MaxTodos: cfg.Tenants.MaxTodos, PerTenant: cfg.Tenants.MaxTodosPerTenant}
+	todoServer.userIDs = cfg.SafetyCulture.UserIDs
+	pb.RegisterTodoServiceServer(grpcServer, todoServer)
+	log.Printf("gRPC server is running on %s", cfg.ListenAddr)
 	if err := grpcServer.Serve(lis); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
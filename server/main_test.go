@@ -0,0 +1,705 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/auth"
+	"github.com/jerryhong21/todo-grpc/config"
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/external/scfake"
+	"github.com/jerryhong21/todo-grpc/idempotency"
+	"github.com/jerryhong21/todo-grpc/outbox"
+	"github.com/jerryhong21/todo-grpc/policy"
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/reconcile"
+	"github.com/jerryhong21/todo-grpc/store"
+	"github.com/jerryhong21/todo-grpc/tenant"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const scToken = "test-token"
+
+// callers in the API keys file of servers started withAuth, by key
+var testKeys = map[string]struct{ subject, tenant, role string }{
+	"alice-key": {"alice", "team-a", "member"},
+	"bob-key":   {"bob", "team-a", "member"},
+	"carol-key": {"carol", "team-b", "member"},
+	"dave-key":  {"dave", "team-a", "viewer"},
+}
+
+// testPolicy is the authorization policy of servers started withAuth
+const testPolicy = `
+roles:
+  viewer:
+    permissions: [todos.read]
+  member:
+    includes: [viewer]
+    permissions: [todos.write, todos.sync]
+methods:
+  /todo.TodoService/GetTodo: todos.read
+  /todo.TodoService/ListTodos: todos.read
+  /todo.TodoService/CreateTodo: todos.write
+  /todo.TodoService/UpdateTodo: todos.write
+  /todo.TodoService/BulkDeleteTodo: todos.write
+  /todo.TodoService/AssignTodo: todos.write
+  /todo.TodoService/ShareTodo: todos.write
+  /todo.TodoService/SyncNow: todos.sync
+`
+
+type testServer struct {
+	client pb.TodoServiceClient
+	fake   *scfake.Server
+	todos  *store.MemoryStore
+	worker *outbox.Worker
+	server *server
+}
+
+// startServer serves a todo server over bufconn with the interceptors main sets up, backed by a fake SC.
+// withAuth requires the keys in testKeys and enforces testPolicy.
+func startServer(t *testing.T, withAuth bool) *testServer {
+	fake := scfake.New(scToken)
+	t.Cleanup(fake.Close)
+	sc := external.NewSCClient(fake.URL, scToken)
+	sc.HTTPClient.Transport.(*external.BreakerTransport).Base.(*external.RetryTransport).MaxAttempts = 1
+	sc.Breaker.FailureThreshold = 1000
+
+	todos := store.NewMemoryStore()
+	worker := outbox.NewWorker(todos, sc)
+	worker.BaseDelay = time.Millisecond
+	worker.MaxDelay = time.Millisecond
+	reconciler := reconcile.NewReconciler(todos, sc, worker)
+	todoServer := NewServer(todos, sc, worker, reconciler)
+
+	var authenticator *auth.Authenticator
+	var engine *policy.Engine
+	if withAuth {
+		keysFile := "keys:\n"
+		for key, caller := range testKeys {
+			sum := sha256.Sum256([]byte(key))
+			keysFile += "  - subject: " + caller.subject + "\n    tenant: " + caller.tenant + "\n    roles: [" + caller.role + "]\n    sha256: " + hex.EncodeToString(sum[:]) + "\n"
+		}
+		dir := t.TempDir()
+		keysPath, policyPath := filepath.Join(dir, "keys.yaml"), filepath.Join(dir, "policy.yaml")
+		if err := os.WriteFile(keysPath, []byte(keysFile), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(policyPath, []byte(testPolicy), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		authenticator, err = auth.New(auth.Options{APIKeysFile: keysPath})
+		if err != nil {
+			t.Fatalf("auth.New: %v", err)
+		}
+		engine, err = policy.Load(policyPath)
+		if err != nil {
+			t.Fatalf("policy.Load: %v", err)
+		}
+	}
+	unary, stream := interceptors(authenticator, engine, idempotency.NewInterceptor(todos))
+
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	pb.RegisterTodoServiceServer(grpcServer, todoServer)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testServer{client: pb.NewTodoServiceClient(conn), fake: fake, todos: todos, worker: worker, server: todoServer}
+}
+
+func as(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), auth.MetadataKey, "Bearer "+key)
+}
+
+func inTenant(name string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), tenant.Header, name)
+}
+
+func list(t *testing.T, c pb.TodoServiceClient, ctx context.Context) []*pb.Todo {
+	t.Helper()
+	stream, err := c.ListTodos(ctx, &pb.ListTodosRequest{})
+	if err != nil {
+		t.Fatalf("ListTodos: %v", err)
+	}
+	var todos []*pb.Todo
+	for {
+		todo, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return todos
+		}
+		if err != nil {
+			t.Fatalf("ListTodos: %v", err)
+		}
+		todos = append(todos, todo)
+	}
+}
+
+func TestTodoLifecycle(t *testing.T) {
+	s := startServer(t, false)
+	ctx := context.Background()
+
+	created, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{Title: "write tests", Description: "all of them"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	if created.GetSyncState() != pb.SyncState_SYNC_STATE_SYNCED || created.GetVersion() != 1 {
+		t.Fatalf("CreateTodo returned %v, want it synced at version 1", created)
+	}
+	if action, ok := s.fake.Action(created.GetId()); !ok || action.Title != "write tests" {
+		t.Fatalf("SC has %+v, want the new action", action)
+	}
+
+	got, err := s.client.GetTodo(ctx, &pb.GetTodoRequest{Id: created.GetId()})
+	if err != nil || got.GetTitle() != "write tests" {
+		t.Fatalf("GetTodo returned %v, %v", got, err)
+	}
+
+	updated, err := s.client.UpdateTodo(ctx, &pb.UpdateTodoRequest{
+		Id:         created.GetId(),
+		Completed:  true,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"completed"}},
+		Version:    1,
+	})
+	if err != nil {
+		t.Fatalf("UpdateTodo: %v", err)
+	}
+	if !updated.GetCompleted() || updated.GetVersion() != 2 || updated.GetTitle() != "write tests" {
+		t.Fatalf("UpdateTodo returned %v, want it completed at version 2 with its title kept", updated)
+	}
+	if action, _ := s.fake.Action(created.GetId()); !action.Completed() {
+		t.Fatal("the action wasn't completed in SC")
+	}
+
+	_, err = s.client.UpdateTodo(ctx, &pb.UpdateTodoRequest{Id: created.GetId(), Title: "stale", Version: 1})
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("UpdateTodo at a stale version returned %v, want ABORTED", err)
+	}
+
+	if todos := list(t, s.client, ctx); len(todos) != 1 || todos[0].GetId() != created.GetId() {
+		t.Fatalf("ListTodos returned %v", todos)
+	}
+
+	missing := "01a14585-3fbe-715e-b705-545cc16442c2"
+	res, err := s.client.BulkDeleteTodo(ctx, &pb.BulkDeleteTodoRequest{Ids: []string{created.GetId(), missing}})
+	if err != nil {
+		t.Fatalf("BulkDeleteTodo: %v", err)
+	}
+	want := map[string]pb.DeleteStatus{created.GetId(): pb.DeleteStatus_DELETE_STATUS_DELETED, missing: pb.DeleteStatus_DELETE_STATUS_NOT_FOUND}
+	if len(res.GetResults()) != 2 {
+		t.Fatalf("BulkDeleteTodo returned %v, want a result per id", res.GetResults())
+	}
+	for _, r := range res.GetResults() {
+		if r.GetStatus() != want[r.GetId()] {
+			t.Fatalf("delete of %s is %s, want %s", r.GetId(), r.GetStatus(), want[r.GetId()])
+		}
+	}
+	if _, ok := s.fake.Action(created.GetId()); ok {
+		t.Fatal("the action is still in SC")
+	}
+	if _, err := s.client.GetTodo(ctx, &pb.GetTodoRequest{Id: created.GetId()}); status.Code(err) != codes.NotFound {
+		t.Fatalf("GetTodo after deleting returned %v, want NOT_FOUND", err)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	s := startServer(t, false)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"id that isn't a UUID", func() error {
+			_, err := s.client.GetTodo(ctx, &pb.GetTodoRequest{Id: "not-a-uuid"})
+			return err
+		}},
+		{"empty title", func() error {
+			_, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{})
+			return err
+		}},
+		{"negative page size", func() error {
+			stream, err := s.client.ListTodos(ctx, &pb.ListTodosRequest{PageSize: -1})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("got %v, want INVALID_ARGUMENT", err)
+			}
+		})
+	}
+	if s.fake.Requests() != 0 {
+		t.Fatal("invalid requests reached SC")
+	}
+}
+
+func TestIdempotentCreate(t *testing.T) {
+	s := startServer(t, false)
+	req := &pb.CreateTodoRequest{Title: "once", IdempotencyKey: "retry-me"}
+
+	first, err := s.client.CreateTodo(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	var header metadata.MD
+	again, err := s.client.CreateTodo(context.Background(), req, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("retried CreateTodo: %v", err)
+	}
+	if again.GetId() != first.GetId() || len(header.Get(idempotency.ReplayedHeader)) == 0 {
+		t.Fatalf("retry created %s, want %s replayed", again.GetId(), first.GetId())
+	}
+
+	_, err = s.client.CreateTodo(context.Background(), &pb.CreateTodoRequest{Title: "something else", IdempotencyKey: "retry-me"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("reusing the key for another request returned %v, want INVALID_ARGUMENT", err)
+	}
+	if todos := list(t, s.client, context.Background()); len(todos) != 1 {
+		t.Fatalf("%d todos stored, want 1", len(todos))
+	}
+}
+
+func TestSCOutage(t *testing.T) {
+	s := startServer(t, false)
+	ctx := context.Background()
+	s.fake.InjectFault(scfake.Fault{Status: 503, Code: codes.Unavailable})
+
+	created, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{Title: "queued"})
+	if err != nil {
+		t.Fatalf("CreateTodo while SC is down: %v", err)
+	}
+	if created.GetSyncState() != pb.SyncState_SYNC_STATE_PENDING {
+		t.Fatalf("CreateTodo returned %v, want it pending", created)
+	}
+
+	s.fake.ClearFaults()
+	time.Sleep(5 * time.Millisecond)
+	if err := s.worker.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	got, err := s.client.GetTodo(ctx, &pb.GetTodoRequest{Id: created.GetId()})
+	if err != nil || got.GetSyncState() != pb.SyncState_SYNC_STATE_SYNCED {
+		t.Fatalf("GetTodo after SC came back returned %v, %v, want it synced", got, err)
+	}
+}
+
+func TestSlowSCAnswersBeforeTheDeadline(t *testing.T) {
+	s := startServer(t, false)
+	s.fake.SetLatency(2 * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	created, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{Title: "slow", IdempotencyKey: "slow"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("CreateTodo took %s, its deadline was 500ms", elapsed)
+	}
+	if created.GetSyncState() != pb.SyncState_SYNC_STATE_PENDING {
+		t.Fatalf("CreateTodo returned %v, want it pending", created)
+	}
+
+	// the retry a client makes after its deadline must not create a second todo
+	again, err := s.client.CreateTodo(context.Background(), &pb.CreateTodoRequest{Title: "slow", IdempotencyKey: "slow"})
+	if err != nil || again.GetId() != created.GetId() {
+		t.Fatalf("retry returned %v, %v, want %s replayed", again, err, created.GetId())
+	}
+}
+
+func TestTenants(t *testing.T) {
+	s := startServer(t, false)
+
+	a, err := s.client.CreateTodo(inTenant("team-a"), &pb.CreateTodoRequest{Title: "a's"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	if a.GetTenant() != "team-a" {
+		t.Fatalf("todo created in team-a has tenant %q", a.GetTenant())
+	}
+
+	if _, err := s.client.GetTodo(inTenant("team-b"), &pb.GetTodoRequest{Id: a.GetId()}); status.Code(err) != codes.NotFound {
+		t.Fatalf("team-b got team-a's todo: %v", err)
+	}
+	if todos := list(t, s.client, inTenant("team-b")); len(todos) != 0 {
+		t.Fatalf("team-b listed %v", todos)
+	}
+	// an id of their own choosing would tell them whether another tenant has it
+	_, err = s.client.CreateTodo(inTenant("team-b"), &pb.CreateTodoRequest{Id: a.GetId(), Title: "b's"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("team-b creating with an id returned %v, want INVALID_ARGUMENT", err)
+	}
+
+	// each tenant is only told about the changes to its own todos
+	for _, tt := range []struct {
+		tenant      string
+		wantUpdated int32
+	}{{"team-b", 0}, {"team-a", 1}} {
+		action, _ := s.fake.Action(a.GetId())
+		action.Title = "edited in SC for " + tt.tenant
+		s.fake.PutAction(action)
+		res, err := s.client.SyncNow(inTenant(tt.tenant), &pb.SyncNowRequest{})
+		if err != nil {
+			t.Fatalf("SyncNow: %v", err)
+		}
+		if res.GetUpdated() != tt.wantUpdated {
+			t.Fatalf("SyncNow for %s counted %d updates, want %d", tt.tenant, res.GetUpdated(), tt.wantUpdated)
+		}
+	}
+}
+
+func TestTenantHeaderMustMatchCredentials(t *testing.T) {
+	s := startServer(t, true)
+	ctx := metadata.AppendToOutgoingContext(as("alice-key"), tenant.Header, "team-b")
+	if _, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{Title: "in someone else's tenant"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("alice naming team-b got %v, want PERMISSION_DENIED", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(as("alice-key"), tenant.Header, "team-a")
+	if _, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{Title: "in her own tenant"}); err != nil {
+		t.Fatalf("alice naming her own tenant: %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(as("alice-key"), tenant.Header, "not a tenant")
+	if _, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{Title: "t"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("an invalid tenant got %v, want INVALID_ARGUMENT", err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	s := startServer(t, true)
+
+	// dave only has the viewer role
+	if got := list(t, s.client, as("dave-key")); len(got) != 0 {
+		t.Fatalf("dave listed %d todos, want none", len(got))
+	}
+	_, err := s.client.CreateTodo(as("dave-key"), &pb.CreateTodoRequest{Title: "dave's"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("a viewer's CreateTodo got %v, want PERMISSION_DENIED", err)
+	}
+	var info *errdetails.ErrorInfo
+	for _, d := range status.Convert(err).Details() {
+		if i, ok := d.(*errdetails.ErrorInfo); ok {
+			info = i
+		}
+	}
+	if info.GetReason() != policy.ReasonMissingPermission || info.GetMetadata()["permission"] != "todos.write" {
+		t.Fatalf("error carries %v, want the missing todos.write permission", info)
+	}
+
+	// requests are only validated once the caller may make them
+	if _, err := s.client.CreateTodo(as("dave-key"), &pb.CreateTodoRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("a viewer's invalid CreateTodo got %v, want PERMISSION_DENIED", err)
+	}
+	if _, err := s.client.GetTodo(as("dave-key"), &pb.GetTodoRequest{Id: "nope"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("a viewer's invalid GetTodo got %v, want INVALID_ARGUMENT", err)
+	}
+	if _, err := s.client.SyncNow(as("dave-key"), &pb.SyncNowRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("a viewer's SyncNow got %v, want PERMISSION_DENIED", err)
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	s := startServer(t, false)
+	s.server.quotas = tenant.Quotas{MaxTodos: 2, PerTenant: map[string]int{"big": 3}}
+
+	var created []*pb.Todo
+	for i := 0; i < 2; i++ {
+		todo, err := s.client.CreateTodo(inTenant("team-a"), &pb.CreateTodoRequest{Title: "t"})
+		if err != nil {
+			t.Fatalf("CreateTodo %d: %v", i, err)
+		}
+		created = append(created, todo)
+	}
+	_, err := s.client.CreateTodo(inTenant("team-a"), &pb.CreateTodoRequest{Title: "one too many"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("CreateTodo over the quota got %v, want RESOURCE_EXHAUSTED", err)
+	}
+	var failure *errdetails.QuotaFailure
+	for _, d := range status.Convert(err).Details() {
+		if f, ok := d.(*errdetails.QuotaFailure); ok {
+			failure = f
+		}
+	}
+	if len(failure.GetViolations()) != 1 || failure.GetViolations()[0].GetSubject() != "tenant:team-a" {
+		t.Fatalf("error carries %v, want a QuotaFailure for tenant:team-a", failure)
+	}
+
+	// the quota is per tenant, and a tenant can have its own
+	for i := 0; i < 3; i++ {
+		if _, err := s.client.CreateTodo(inTenant("big"), &pb.CreateTodoRequest{Title: "t"}); err != nil {
+			t.Fatalf("CreateTodo %d in a tenant with a bigger quota: %v", i, err)
+		}
+	}
+
+	// deleting a todo makes room again
+	if _, err := s.client.BulkDeleteTodo(inTenant("team-a"), &pb.BulkDeleteTodoRequest{Ids: []string{created[0].GetId()}}); err != nil {
+		t.Fatalf("BulkDeleteTodo: %v", err)
+	}
+	if _, err := s.client.CreateTodo(inTenant("team-a"), &pb.CreateTodoRequest{Title: "t"}); err != nil {
+		t.Fatalf("CreateTodo after deleting one: %v", err)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	s := startServer(t, true)
+
+	if _, err := s.client.CreateTodo(context.Background(), &pb.CreateTodoRequest{Title: "anonymous"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("CreateTodo without a key returned %v, want UNAUTHENTICATED", err)
+	}
+
+	todo, err := s.client.CreateTodo(as("alice-key"), &pb.CreateTodoRequest{Title: "alice's"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	if todo.GetOwner() != "alice" || todo.GetTenant() != "team-a" {
+		t.Fatalf("todo is owned by %q in %q, want alice in team-a", todo.GetOwner(), todo.GetTenant())
+	}
+
+	// bob shares alice's tenant but not her todo
+	if _, err := s.client.GetTodo(as("bob-key"), &pb.GetTodoRequest{Id: todo.GetId()}); status.Code(err) != codes.NotFound {
+		t.Fatalf("bob got alice's private todo: %v", err)
+	}
+
+	shared, err := s.client.ShareTodo(as("alice-key"), &pb.ShareTodoRequest{Id: todo.GetId(), SharedWith: []string{"bob"}})
+	if err != nil {
+		t.Fatalf("ShareTodo: %v", err)
+	}
+	if _, err := s.client.GetTodo(as("bob-key"), &pb.GetTodoRequest{Id: todo.GetId()}); err != nil {
+		t.Fatalf("bob can't get a todo shared with him: %v", err)
+	}
+	if _, err := s.client.UpdateTodo(as("bob-key"), &pb.UpdateTodoRequest{Id: todo.GetId(), Title: "bob's now"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("bob updated a todo only shared with him: %v", err)
+	}
+
+	if _, err := s.client.AssignTodo(as("alice-key"), &pb.AssignTodoRequest{Id: todo.GetId(), Assignees: []string{"bob"}, Version: shared.GetVersion()}); err != nil {
+		t.Fatalf("AssignTodo: %v", err)
+	}
+	if _, err := s.client.UpdateTodo(as("bob-key"), &pb.UpdateTodoRequest{Id: todo.GetId(), Title: "assigned to bob"}); err != nil {
+		t.Fatalf("bob can't update a todo assigned to him: %v", err)
+	}
+
+	// carol is in another tenant, so the todo doesn't exist for her at all
+	res, err := s.client.BulkDeleteTodo(as("carol-key"), &pb.BulkDeleteTodoRequest{Ids: []string{todo.GetId()}})
+	if err != nil {
+		t.Fatalf("BulkDeleteTodo: %v", err)
+	}
+	if got := res.GetResults()[0].GetStatus(); got != pb.DeleteStatus_DELETE_STATUS_NOT_FOUND {
+		t.Fatalf("carol's delete is %s, want not found", got)
+	}
+	if _, ok := s.fake.Action(todo.GetId()); !ok {
+		t.Fatal("carol deleted the action in SC")
+	}
+}
+
+func TestCallerTokens(t *testing.T) {
+	s := startServer(t, false)
+	s.server.tokenPolicy = config.TokenPassthrough
+
+	shared, err := s.client.CreateTodo(context.Background(), &pb.CreateTodoRequest{Title: "with the shared token"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), scTokenHeader, scToken)
+	own, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{Title: "with the caller's token"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	if shared.GetCreatedWithCallerToken() || !own.GetCreatedWithCallerToken() {
+		t.Fatalf("created_with_caller_token is %v with the shared token and %v with the caller's, want false and true",
+			shared.GetCreatedWithCallerToken(), own.GetCreatedWithCallerToken())
+	}
+}
+
+// listPage lists one page, returning the token for the next one
+func listPage(t *testing.T, c pb.TodoServiceClient, req *pb.ListTodosRequest) ([]*pb.Todo, string) {
+	t.Helper()
+	stream, err := c.ListTodos(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ListTodos: %v", err)
+	}
+	var todos []*pb.Todo
+	for {
+		todo, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ListTodos: %v", err)
+		}
+		todos = append(todos, todo)
+	}
+	var next string
+	if values := stream.Trailer().Get("next-page-token"); len(values) > 0 {
+		next = values[0]
+	}
+	return todos, next
+}
+
+// putTodos stores n todos straight into the store, created a second apart
+func (s *testServer) putTodos(t *testing.T, n int, todo func(i int) *pb.Todo) {
+	t.Helper()
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < n; i++ {
+		td := todo(i)
+		td.Id = fmt.Sprintf("01a14585-3fbe-715e-b705-%012d", i)
+		td.CreatedAt = timestamppb.New(start.Add(time.Duration(i) * time.Second))
+		td.SyncState = pb.SyncState_SYNC_STATE_SYNCED
+		td.Version = 1
+		if err := s.todos.Put(context.Background(), td); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+}
+
+func TestListTodosPages(t *testing.T) {
+	s := startServer(t, false)
+	s.putTodos(t, 30, func(i int) *pb.Todo {
+		title := fmt.Sprintf("chore %d", i)
+		if i%3 == 0 {
+			title = fmt.Sprintf("errand %d", i)
+		}
+		return &pb.Todo{Title: title, Completed: i%2 == 0}
+	})
+
+	// completed chores created after the sixth todo: 8, 10, 14, 16, 20, 22, 26, 28
+	var want []string
+	for i := 6; i < 30; i += 2 {
+		if i%3 != 0 {
+			want = append(want, fmt.Sprintf("chore %d", i))
+		}
+	}
+
+	var got []string
+	pages := 0
+	req := &pb.ListTodosRequest{
+		Completed:     proto.Bool(true),
+		TitleContains: "CHORE",
+		CreatedAfter:  timestamppb.New(time.Unix(1700000005, 0)),
+		PageSize:      3,
+	}
+	for {
+		todos, next := listPage(t, s.client, req)
+		pages++
+		if len(todos) > 3 {
+			t.Fatalf("page %d has %d todos, page_size is 3", pages, len(todos))
+		}
+		for _, todo := range todos {
+			got = append(got, todo.GetTitle())
+		}
+		if next == "" {
+			break
+		}
+		// the filters are sent again with the token
+		req.PageToken = next
+	}
+	if pages != 3 || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("%d pages listed %v, want 3 pages of %v", pages, got, want)
+	}
+}
+
+func TestListTodosPageSize(t *testing.T) {
+	s := startServer(t, false)
+	s.putTodos(t, 1001, func(i int) *pb.Todo { return &pb.Todo{Title: "todo"} })
+
+	tests := []struct {
+		pageSize int32
+		want     int
+	}{
+		{pageSize: 0, want: 50},
+		{pageSize: 10, want: 10},
+		{pageSize: 5000, want: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.pageSize), func(t *testing.T) {
+			todos, next := listPage(t, s.client, &pb.ListTodosRequest{PageSize: tt.pageSize})
+			if len(todos) != tt.want || next == "" {
+				t.Fatalf("got %d todos and next page token %q, want %d and a token", len(todos), next, tt.want)
+			}
+		})
+	}
+}
+
+func TestListTodosInvalidPageToken(t *testing.T) {
+	s := startServer(t, false)
+	for _, token := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("no-slash")), base64.RawURLEncoding.EncodeToString([]byte("abc/id"))} {
+		stream, err := s.client.ListTodos(context.Background(), &pb.ListTodosRequest{PageToken: token})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("page token %q got %v, want INVALID_ARGUMENT", token, err)
+		}
+	}
+}
+
+func TestAssigneesReachSC(t *testing.T) {
+	s := startServer(t, false)
+	s.server.userIDs = map[string]string{"bob": "user_5c2a"}
+	ctx := context.Background()
+
+	todo, err := s.client.CreateTodo(ctx, &pb.CreateTodoRequest{Title: "assign me"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	// SC is down when the assignees change, so they go out with the next drain
+	s.fake.InjectFault(scfake.Fault{Method: http.MethodPut, Status: http.StatusServiceUnavailable, Code: codes.Unavailable})
+	assigned, err := s.client.AssignTodo(ctx, &pb.AssignTodoRequest{Id: todo.GetId(), Assignees: []string{"bob", "carol"}})
+	if err != nil {
+		t.Fatalf("AssignTodo: %v", err)
+	}
+	if assigned.GetSyncState() != pb.SyncState_SYNC_STATE_PENDING {
+		t.Fatalf("AssignTodo returned %v while SC is down, want it pending", assigned)
+	}
+
+	s.fake.ClearFaults()
+	time.Sleep(5 * time.Millisecond)
+	if err := s.worker.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	action, _ := s.fake.Action(todo.GetId())
+	// bob is mapped to an SC user id, carol isn't mapped so the subject is sent as it is
+	want := external.Assignees([]string{"user_5c2a", "carol"})
+	if fmt.Sprint(action.Collaborators) != fmt.Sprint(want) {
+		t.Fatalf("SC action has collaborators %+v, want %+v", action.Collaborators, want)
+	}
+	for _, c := range action.Collaborators {
+		if c.AssignedRole != external.RoleAssignee || c.CollaboratorType != external.CollaboratorUser {
+			t.Fatalf("collaborator %+v isn't a user assignee", c)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRecord is the stored response to a request made with an idempotency key
+type IdempotencyRecord struct {
+	Method       string // full gRPC method, keys are scoped to it
+	Key          string // prefixed with the caller when they are authenticated
+	RequestHash  []byte // fingerprint of the request, a key can't be reused for a different one
+	ResponseType string // full proto name of Response
+	Response     []byte // proto encoded response
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+// IdempotencyStore remembers responses so retried requests can be answered without running them again
+type IdempotencyStore interface {
+	// GetIdempotency returns the record for method and key, or ErrNotFound. Expired records may still be returned.
+	GetIdempotency(ctx context.Context, method, key string) (*IdempotencyRecord, error)
+	// PutIdempotency creates the record or replaces the one with the same method and key
+	PutIdempotency(ctx context.Context, rec *IdempotencyRecord) error
+	// DeleteExpiredIdempotency removes the records that expired before now and returns how many were removed
+	DeleteExpiredIdempotency(ctx context.Context, now time.Time) (int, error)
+}
@@ -0,0 +1,301 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// MemoryStore keeps todos in a map, everything is lost when the server stops.
+// Todos are copied on the way in and out so callers can't modify stored state by accident.
+// It is safe for concurrent use, transactions run one at a time.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	todos map[string]*pb.Todo // maps todo Ids to todo
+
+	ops      []*Operation // outbox, in ID order
+	nextOpID int64
+
+	idempotency map[string]*IdempotencyRecord // keyed by method + "\x00" + key
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		todos:       make(map[string]*pb.Todo),
+		idempotency: make(map[string]*IdempotencyRecord),
+	}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*pb.Todo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.get(id)
+}
+
+func (m *MemoryStore) Put(ctx context.Context, todo *pb.Todo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.todos[todo.GetId()] = proto.Clone(todo).(*pb.Todo)
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.todos[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.todos, id)
+	return nil
+}
+
+func (m *MemoryStore) List(ctx context.Context) ([]*pb.Todo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	todos := make([]*pb.Todo, 0, len(m.todos))
+	for _, todo := range m.todos {
+		todos = append(todos, proto.Clone(todo).(*pb.Todo))
+	}
+	return todos, nil
+}
+
+// get reads a todo, m.mu must be held
+func (m *MemoryStore) get(id string) (*pb.Todo, error) {
+	todo, ok := m.todos[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return proto.Clone(todo).(*pb.Todo), nil
+}
+
+// Tx stages writes in a memoryTx and only copies them into the map once fn succeeds.
+// The store stays locked until then, so what fn reads can't change under it.
+func (m *MemoryStore) Tx(ctx context.Context, fn func(tx TodoStore) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &memoryTx{
+		parent:    m,
+		puts:      make(map[string]*pb.Todo),
+		deletes:   make(map[string]bool),
+		opUpdates: make(map[int64]*Operation),
+		opDeletes: make(map[int64]bool),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	for id := range tx.deletes {
+		delete(m.todos, id)
+	}
+	for id, todo := range tx.puts {
+		m.todos[id] = todo
+	}
+
+	kept := m.ops[:0]
+	for _, op := range m.ops {
+		if tx.opDeletes[op.ID] {
+			continue
+		}
+		if updated, ok := tx.opUpdates[op.ID]; ok {
+			op = updated
+		}
+		kept = append(kept, op)
+	}
+	m.ops = append(kept, tx.enqueued...)
+	return nil
+}
+
+func (m *MemoryStore) Enqueue(ctx context.Context, op *Operation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextOpID++
+	op.ID = m.nextOpID
+	copied := *op
+	m.ops = append(m.ops, &copied)
+	return nil
+}
+
+func (m *MemoryStore) PendingOperations(ctx context.Context) ([]*Operation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pending := []*Operation{}
+	for _, op := range m.ops {
+		if !op.Failed {
+			copied := *op
+			pending = append(pending, &copied)
+		}
+	}
+	return pending, nil
+}
+
+func (m *MemoryStore) UpdateOperation(ctx context.Context, op *Operation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.ops {
+		if existing.ID == op.ID {
+			copied := *op
+			m.ops[i] = &copied
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MemoryStore) DeleteOperation(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, op := range m.ops {
+		if op.ID == id {
+			m.ops = append(m.ops[:i], m.ops[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetIdempotency(ctx context.Context, method, key string) (*IdempotencyRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rec, ok := m.idempotency[method+"\x00"+key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *rec
+	return &copied, nil
+}
+
+func (m *MemoryStore) PutIdempotency(ctx context.Context, rec *IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *rec
+	m.idempotency[rec.Method+"\x00"+rec.Key] = &copied
+	return nil
+}
+
+func (m *MemoryStore) DeleteExpiredIdempotency(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for id, rec := range m.idempotency {
+		if rec.ExpiresAt.Before(now) {
+			delete(m.idempotency, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// memoryTx overlays uncommitted writes on top of the parent store
+type memoryTx struct {
+	parent  *MemoryStore
+	puts    map[string]*pb.Todo
+	deletes map[string]bool
+
+	enqueued  []*Operation
+	opUpdates map[int64]*Operation
+	opDeletes map[int64]bool
+}
+
+func (t *memoryTx) Get(ctx context.Context, id string) (*pb.Todo, error) {
+	if todo, ok := t.puts[id]; ok {
+		return proto.Clone(todo).(*pb.Todo), nil
+	}
+	if t.deletes[id] {
+		return nil, ErrNotFound
+	}
+	return t.parent.get(id)
+}
+
+func (t *memoryTx) Put(ctx context.Context, todo *pb.Todo) error {
+	delete(t.deletes, todo.GetId())
+	t.puts[todo.GetId()] = proto.Clone(todo).(*pb.Todo)
+	return nil
+}
+
+func (t *memoryTx) Delete(ctx context.Context, id string) error {
+	if _, err := t.Get(ctx, id); err != nil {
+		return err
+	}
+	delete(t.puts, id)
+	t.deletes[id] = true
+	return nil
+}
+
+func (t *memoryTx) List(ctx context.Context) ([]*pb.Todo, error) {
+	todos := []*pb.Todo{}
+	for id, todo := range t.parent.todos {
+		if t.deletes[id] {
+			continue
+		}
+		if _, ok := t.puts[id]; ok {
+			continue
+		}
+		todos = append(todos, proto.Clone(todo).(*pb.Todo))
+	}
+	for _, todo := range t.puts {
+		todos = append(todos, proto.Clone(todo).(*pb.Todo))
+	}
+	return todos, nil
+}
+
+// Nested transactions just join the outer one
+func (t *memoryTx) Tx(ctx context.Context, fn func(tx TodoStore) error) error {
+	return fn(t)
+}
+
+func (t *memoryTx) Enqueue(ctx context.Context, op *Operation) error {
+	// ids come from the parent so they stay unique, a rollback just leaves a gap
+	t.parent.nextOpID++
+	op.ID = t.parent.nextOpID
+	copied := *op
+	t.enqueued = append(t.enqueued, &copied)
+	return nil
+}
+
+func (t *memoryTx) PendingOperations(ctx context.Context) ([]*Operation, error) {
+	pending := []*Operation{}
+	for _, op := range append(append([]*Operation{}, t.parent.ops...), t.enqueued...) {
+		if t.opDeletes[op.ID] {
+			continue
+		}
+		if updated, ok := t.opUpdates[op.ID]; ok {
+			op = updated
+		}
+		if !op.Failed {
+			copied := *op
+			pending = append(pending, &copied)
+		}
+	}
+	return pending, nil
+}
+
+func (t *memoryTx) UpdateOperation(ctx context.Context, op *Operation) error {
+	copied := *op
+	for i, staged := range t.enqueued {
+		if staged.ID == op.ID {
+			t.enqueued[i] = &copied
+			return nil
+		}
+	}
+	t.opUpdates[op.ID] = &copied
+	return nil
+}
+
+func (t *memoryTx) DeleteOperation(ctx context.Context, id int64) error {
+	for i, staged := range t.enqueued {
+		if staged.ID == id {
+			t.enqueued = append(t.enqueued[:i], t.enqueued[i+1:]...)
+			return nil
+		}
+	}
+	t.opDeletes[id] = true
+	return nil
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migration is one versioned schema change, applied at most once per database
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// migrations are applied in order. Never edit or reorder one that has shipped, append a new one instead.
+//
+// Columns for pb.Todo fields don't need a migration, syncTodoColumns adds them
+// whenever a field is added to the proto.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create todos",
+		sql:     `CREATE TABLE todos (id TEXT PRIMARY KEY)`,
+	},
+	{
+		version: 2,
+		name:    "create outbox",
+		// times are unix nanoseconds
+		sql: `CREATE TABLE outbox (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind         TEXT NOT NULL,
+			todo_id      TEXT NOT NULL,
+			payload      BLOB NOT NULL,
+			attempts     INTEGER NOT NULL DEFAULT 0,
+			next_attempt INTEGER NOT NULL DEFAULT 0,
+			last_error   TEXT NOT NULL DEFAULT '',
+			failed       INTEGER NOT NULL DEFAULT 0,
+			created_at   INTEGER NOT NULL
+		)`,
+	},
+	{
+		version: 3,
+		name:    "create idempotency keys",
+		sql: `CREATE TABLE idempotency_keys (
+			method        TEXT NOT NULL,
+			key           TEXT NOT NULL,
+			request_hash  BLOB NOT NULL,
+			response_type TEXT NOT NULL,
+			response      BLOB NOT NULL,
+			created_at    INTEGER NOT NULL,
+			expires_at    INTEGER NOT NULL,
+			PRIMARY KEY (method, key)
+		)`,
+	},
+}
+
+// migrate applies any pending migrations and then makes sure every pb.Todo field has a column
+func migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	err = db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return err
+		}
+	}
+
+	return syncTodoColumns(ctx, db)
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.version, m.name); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+	return tx.Commit()
+}
+
+// syncTodoColumns adds a column for every pb.Todo field the todos table doesn't have yet.
+// Columns are never dropped, so removing a field from the proto leaves its data in place.
+func syncTodoColumns(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info('todos')")
+	if err != nil {
+		return fmt.Errorf("failed to read todos columns: %w", err)
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, fd := range todoFields() {
+		name := string(fd.Name())
+		if existing[name] {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE todos ADD COLUMN %s %s", name, columnType(fd))
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", name, err)
+		}
+	}
+	return nil
+}
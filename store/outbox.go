@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// OperationKind is the SafetyCulture call an outbox operation replays
+type OperationKind string
+
+const (
+	OperationCreate OperationKind = "create"
+	OperationUpdate OperationKind = "update"
+	OperationDelete OperationKind = "delete"
+)
+
+// Operation is a SafetyCulture write waiting in the outbox.
+// It is written in the same transaction as the local change, so neither can be lost without the other.
+type Operation struct {
+	ID          int64 // assigned by Enqueue, operations run in ID order
+	Kind        OperationKind
+	TodoID      string // for deletes, the first of the deleted ids
+	Payload     []byte // JSON encoded SC request
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	Failed      bool // gave up, kept for inspection but no longer retried
+	CreatedAt   time.Time
+}
+
+// Outbox is the queue of SafetyCulture writes that haven't been applied yet
+type Outbox interface {
+	// Enqueue adds op to the end of the queue and sets its ID
+	Enqueue(ctx context.Context, op *Operation) error
+	// PendingOperations returns every operation that hasn't failed, oldest first
+	PendingOperations(ctx context.Context) ([]*Operation, error)
+	// UpdateOperation saves the retry state of op
+	UpdateOperation(ctx context.Context, op *Operation) error
+	// DeleteOperation removes an operation once it has been applied
+	DeleteOperation(ctx context.Context, id int64) error
+}
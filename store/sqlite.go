@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	_ "modernc.org/sqlite" // pure Go driver, registers "sqlite"
+)
+
+// SQLiteStore keeps todos in a single SQLite file so they survive restarts.
+// Every field of pb.Todo is stored in a column named after the proto field.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the database at path and brings its schema up to date
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// SQLite only allows one writer, a single connection avoids SQLITE_BUSY between our own requests
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*pb.Todo, error) {
+	return sqlGet(ctx, s.db, id)
+}
+
+func (s *SQLiteStore) Put(ctx context.Context, todo *pb.Todo) error {
+	return sqlPut(ctx, s.db, todo)
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	return sqlDelete(ctx, s.db, id)
+}
+
+func (s *SQLiteStore) List(ctx context.Context) ([]*pb.Todo, error) {
+	return sqlList(ctx, s.db)
+}
+
+func (s *SQLiteStore) Tx(ctx context.Context, fn func(tx TodoStore) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(&sqliteTx{tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type sqliteTx struct {
+	tx *sql.Tx
+}
+
+func (t *sqliteTx) Get(ctx context.Context, id string) (*pb.Todo, error) {
+	return sqlGet(ctx, t.tx, id)
+}
+
+func (t *sqliteTx) Put(ctx context.Context, todo *pb.Todo) error {
+	return sqlPut(ctx, t.tx, todo)
+}
+
+func (t *sqliteTx) Delete(ctx context.Context, id string) error {
+	return sqlDelete(ctx, t.tx, id)
+}
+
+func (t *sqliteTx) List(ctx context.Context) ([]*pb.Todo, error) {
+	return sqlList(ctx, t.tx)
+}
+
+// Nested transactions just join the outer one
+func (t *sqliteTx) Tx(ctx context.Context, fn func(tx TodoStore) error) error {
+	return fn(t)
+}
+
+// querier is the part of *sql.DB and *sql.Tx the queries need
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// todoFields are the pb.Todo fields in column order
+func todoFields() []protoreflect.FieldDescriptor {
+	fields := (&pb.Todo{}).ProtoReflect().Descriptor().Fields()
+	out := make([]protoreflect.FieldDescriptor, fields.Len())
+	for i := range out {
+		out[i] = fields.Get(i)
+	}
+	return out
+}
+
+func todoColumns() []string {
+	columns := []string{}
+	for _, fd := range todoFields() {
+		columns = append(columns, string(fd.Name()))
+	}
+	return columns
+}
+
+func sqlGet(ctx context.Context, q querier, id string) (*pb.Todo, error) {
+	query := fmt.Sprintf("SELECT %s FROM todos WHERE id = ?", strings.Join(todoColumns(), ", "))
+	todos, err := sqlQuery(ctx, q, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(todos) == 0 {
+		return nil, ErrNotFound
+	}
+	return todos[0], nil
+}
+
+func sqlList(ctx context.Context, q querier) ([]*pb.Todo, error) {
+	query := fmt.Sprintf("SELECT %s FROM todos", strings.Join(todoColumns(), ", "))
+	return sqlQuery(ctx, q, query)
+}
+
+func sqlPut(ctx context.Context, q querier, todo *pb.Todo) error {
+	columns := todoColumns()
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+
+	args := []any{}
+	for _, fd := range todoFields() {
+		v, err := encodeColumn(todo, fd)
+		if err != nil {
+			return err
+		}
+		args = append(args, v)
+	}
+
+	query := fmt.Sprintf("INSERT OR REPLACE INTO todos (%s) VALUES (%s)", strings.Join(columns, ", "), placeholders)
+	if _, err := q.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to write todo %s: %w", todo.GetId(), err)
+	}
+	return nil
+}
+
+func sqlDelete(ctx context.Context, q querier, id string) error {
+	res, err := q.ExecContext(ctx, "DELETE FROM todos WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete todo %s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func sqlQuery(ctx context.Context, q querier, query string, args ...any) ([]*pb.Todo, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todos: %w", err)
+	}
+	defer rows.Close()
+
+	fields := todoFields()
+	todos := []*pb.Todo{}
+	for rows.Next() {
+		values := make([]any, len(fields))
+		dest := make([]any, len(fields))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan todo: %w", err)
+		}
+
+		todo := &pb.Todo{}
+		for i, fd := range fields {
+			if err := decodeColumn(todo, fd, values[i]); err != nil {
+				return nil, err
+			}
+		}
+		todos = append(todos, todo)
+	}
+	return todos, rows.Err()
+}
+
+// columnType picks the SQLite column type for a proto field.
+// Scalars get native columns, everything else is stored as an encoded blob.
+func columnType(fd protoreflect.FieldDescriptor) string {
+	if fd.IsList() || fd.IsMap() {
+		return "BLOB"
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return "TEXT NOT NULL DEFAULT ''"
+	case protoreflect.BoolKind, protoreflect.EnumKind,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "INTEGER NOT NULL DEFAULT 0"
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return "REAL NOT NULL DEFAULT 0"
+	default:
+		return "BLOB"
+	}
+}
+
+func encodeColumn(todo *pb.Todo, fd protoreflect.FieldDescriptor) (any, error) {
+	m := todo.ProtoReflect()
+	if columnType(fd) == "BLOB" {
+		if !m.Has(fd) {
+			return nil, nil
+		}
+		// encode a message holding just this field, decodeColumn merges it back
+		single := &pb.Todo{}
+		single.ProtoReflect().Set(fd, m.Get(fd))
+		return proto.Marshal(single)
+	}
+
+	v := m.Get(fd)
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return v.String(), nil
+	case protoreflect.BoolKind:
+		return v.Bool(), nil
+	case protoreflect.EnumKind:
+		return int64(v.Enum()), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return int64(v.Uint()), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float(), nil
+	default:
+		return v.Int(), nil
+	}
+}
+
+func decodeColumn(todo *pb.Todo, fd protoreflect.FieldDescriptor, value any) error {
+	if value == nil {
+		return nil
+	}
+	m := todo.ProtoReflect()
+
+	if columnType(fd) == "BLOB" {
+		b, ok := value.([]byte)
+		if !ok {
+			return fmt.Errorf("column %s: unexpected %T", fd.Name(), value)
+		}
+		return proto.UnmarshalOptions{Merge: true}.Unmarshal(b, todo)
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("column %s: unexpected %T", fd.Name(), value)
+		}
+		m.Set(fd, protoreflect.ValueOfString(v))
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("column %s: unexpected %T", fd.Name(), value)
+		}
+		if fd.Kind() == protoreflect.FloatKind {
+			m.Set(fd, protoreflect.ValueOfFloat32(float32(v)))
+		} else {
+			m.Set(fd, protoreflect.ValueOfFloat64(v))
+		}
+	default:
+		v, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("column %s: unexpected %T", fd.Name(), value)
+		}
+		switch fd.Kind() {
+		case protoreflect.BoolKind:
+			m.Set(fd, protoreflect.ValueOfBool(v != 0))
+		case protoreflect.EnumKind:
+			m.Set(fd, protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)))
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+			m.Set(fd, protoreflect.ValueOfInt32(int32(v)))
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+			m.Set(fd, protoreflect.ValueOfUint32(uint32(v)))
+		case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			m.Set(fd, protoreflect.ValueOfUint64(uint64(v)))
+		default:
+			m.Set(fd, protoreflect.ValueOfInt64(v))
+		}
+	}
+	return nil
+}
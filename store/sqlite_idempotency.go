@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func (s *SQLiteStore) GetIdempotency(ctx context.Context, method, key string) (*IdempotencyRecord, error) {
+	rec := &IdempotencyRecord{Method: method, Key: key}
+	var createdAt, expiresAt int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT request_hash, response_type, response, created_at, expires_at
+		FROM idempotency_keys WHERE method = ? AND key = ?`, method, key).
+		Scan(&rec.RequestHash, &rec.ResponseType, &rec.Response, &createdAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	rec.CreatedAt = fromUnixNanos(createdAt)
+	rec.ExpiresAt = fromUnixNanos(expiresAt)
+	return rec, nil
+}
+
+func (s *SQLiteStore) PutIdempotency(ctx context.Context, rec *IdempotencyRecord) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO idempotency_keys (method, key, request_hash, response_type, response, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		rec.Method, rec.Key, rec.RequestHash, rec.ResponseType, rec.Response, unixNanos(rec.CreatedAt), unixNanos(rec.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteExpiredIdempotency(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < ?", unixNanos(now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *SQLiteStore) Enqueue(ctx context.Context, op *Operation) error {
+	return sqlEnqueue(ctx, s.db, op)
+}
+
+func (s *SQLiteStore) PendingOperations(ctx context.Context) ([]*Operation, error) {
+	return sqlPendingOperations(ctx, s.db)
+}
+
+func (s *SQLiteStore) UpdateOperation(ctx context.Context, op *Operation) error {
+	return sqlUpdateOperation(ctx, s.db, op)
+}
+
+func (s *SQLiteStore) DeleteOperation(ctx context.Context, id int64) error {
+	return sqlDeleteOperation(ctx, s.db, id)
+}
+
+func (t *sqliteTx) Enqueue(ctx context.Context, op *Operation) error {
+	return sqlEnqueue(ctx, t.tx, op)
+}
+
+func (t *sqliteTx) PendingOperations(ctx context.Context) ([]*Operation, error) {
+	return sqlPendingOperations(ctx, t.tx)
+}
+
+func (t *sqliteTx) UpdateOperation(ctx context.Context, op *Operation) error {
+	return sqlUpdateOperation(ctx, t.tx, op)
+}
+
+func (t *sqliteTx) DeleteOperation(ctx context.Context, id int64) error {
+	return sqlDeleteOperation(ctx, t.tx, id)
+}
+
+func sqlEnqueue(ctx context.Context, q querier, op *Operation) error {
+	if op.CreatedAt.IsZero() {
+		op.CreatedAt = time.Now()
+	}
+	res, err := q.ExecContext(ctx,
+		`INSERT INTO outbox (kind, todo_id, payload, attempts, next_attempt, last_error, failed, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		string(op.Kind), op.TodoID, op.Payload, op.Attempts, unixNanos(op.NextAttempt), op.LastError, op.Failed, unixNanos(op.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s operation: %w", op.Kind, err)
+	}
+	op.ID, err = res.LastInsertId()
+	return err
+}
+
+func sqlPendingOperations(ctx context.Context, q querier) ([]*Operation, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, kind, todo_id, payload, attempts, next_attempt, last_error, failed, created_at
+		FROM outbox WHERE failed = 0 ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	ops := []*Operation{}
+	for rows.Next() {
+		op := &Operation{}
+		var kind string
+		var nextAttempt, createdAt int64
+		err := rows.Scan(&op.ID, &kind, &op.TodoID, &op.Payload, &op.Attempts, &nextAttempt, &op.LastError, &op.Failed, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox operation: %w", err)
+		}
+		op.Kind = OperationKind(kind)
+		op.NextAttempt = fromUnixNanos(nextAttempt)
+		op.CreatedAt = fromUnixNanos(createdAt)
+		ops = append(ops, op)
+	}
+	return ops, rows.Err()
+}
+
+func sqlUpdateOperation(ctx context.Context, q querier, op *Operation) error {
+	res, err := q.ExecContext(ctx,
+		`UPDATE outbox SET attempts = ?, next_attempt = ?, last_error = ?, failed = ? WHERE id = ?`,
+		op.Attempts, unixNanos(op.NextAttempt), op.LastError, op.Failed, op.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox operation %d: %w", op.ID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func sqlDeleteOperation(ctx context.Context, q querier, id int64) error {
+	if _, err := q.ExecContext(ctx, "DELETE FROM outbox WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete outbox operation %d: %w", id, err)
+	}
+	return nil
+}
+
+func unixNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNanos(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,33 @@
+// Package store defines where the server keeps its todos.
+//
+// The RPC handlers only talk to the TodoStore interface, so the in-memory
+// map can be swapped for a durable backend or a fake without touching them.
+package store
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/jerryhong21/todo-grpc/proto"
+)
+
+// ErrNotFound is returned when no todo exists with the requested id
+var ErrNotFound = errors.New("todo not found")
+
+// TodoStore persists todos keyed by their id
+type TodoStore interface {
+	// Get returns the todo with the given id, or ErrNotFound
+	Get(ctx context.Context, id string) (*pb.Todo, error)
+	// Put creates the todo or replaces the existing todo with the same id
+	Put(ctx context.Context, todo *pb.Todo) error
+	// Delete removes the todo with the given id, or returns ErrNotFound
+	Delete(ctx context.Context, id string) error
+	// List returns every todo in no particular order
+	List(ctx context.Context) ([]*pb.Todo, error)
+	// Tx runs fn against a transactional view of the store.
+	// Writes made through tx are applied together if fn returns nil and discarded otherwise.
+	Tx(ctx context.Context, fn func(tx TodoStore) error) error
+
+	// Outbox lives next to the todos so a change and its SC operation commit together
+	Outbox
+}
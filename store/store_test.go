@@ -0,0 +1,303 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/store"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// backend is a store under test, every backend has to pass the same tests
+type backend interface {
+	store.TodoStore
+	store.IdempotencyStore
+}
+
+func backends(t *testing.T) map[string]func(t *testing.T) backend {
+	return map[string]func(t *testing.T) backend{
+		"memory": func(t *testing.T) backend {
+			return store.NewMemoryStore()
+		},
+		"sqlite": func(t *testing.T) backend {
+			s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "todos.db"))
+			if err != nil {
+				t.Fatalf("NewSQLiteStore: %v", err)
+			}
+			t.Cleanup(func() { s.Close() })
+			return s
+		},
+	}
+}
+
+// forEachBackend runs test against a fresh store of every backend
+func forEachBackend(t *testing.T, test func(t *testing.T, s backend)) {
+	for name, open := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			test(t, open(t))
+		})
+	}
+}
+
+func newTodo(id string) *pb.Todo {
+	return &pb.Todo{
+		Id:          id,
+		Title:       "title " + id,
+		Description: "description",
+		CreatedAt:   timestamppb.New(time.Unix(1700000000, 0)),
+		SyncState:   pb.SyncState_SYNC_STATE_PENDING,
+		Version:     1,
+		Tenant:      "team-a",
+		Owner:       "alice",
+		Assignees:   []string{"bob", "carol"},
+		SharedWith:  []string{"dave"},
+	}
+}
+
+func TestTodos(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+
+		if _, err := s.Get(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("Get of a missing todo: got %v, want ErrNotFound", err)
+		}
+		if err := s.Delete(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("Delete of a missing todo: got %v, want ErrNotFound", err)
+		}
+
+		todo := newTodo("a")
+		todo.Completed = true
+		todo.DeletedAt = timestamppb.New(time.Unix(1700000100, 0))
+		if err := s.Put(ctx, todo); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		got, err := s.Get(ctx, "a")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if !proto.Equal(got, todo) {
+			t.Fatalf("Get returned %v, want %v", got, todo)
+		}
+
+		// changing what came back must not change what is stored
+		got.Title = "changed"
+		if again, _ := s.Get(ctx, "a"); again.GetTitle() != todo.GetTitle() {
+			t.Fatalf("stored todo changed to %q through a returned copy", again.GetTitle())
+		}
+
+		replaced := newTodo("a")
+		replaced.Title = "replaced"
+		replaced.Assignees = nil
+		if err := s.Put(ctx, replaced); err != nil {
+			t.Fatalf("Put replacing: %v", err)
+		}
+		if got, _ := s.Get(ctx, "a"); !proto.Equal(got, replaced) {
+			t.Fatalf("Get after replacing returned %v, want %v", got, replaced)
+		}
+
+		if err := s.Put(ctx, newTodo("b")); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		todos, err := s.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(todos) != 2 {
+			t.Fatalf("List returned %d todos, want 2", len(todos))
+		}
+
+		if err := s.Delete(ctx, "a"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := s.Get(ctx, "a"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("Get after Delete: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestTx(t *testing.T) {
+	errRollback := errors.New("roll back")
+
+	forEachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		if err := s.Put(ctx, newTodo("kept")); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+
+		err := s.Tx(ctx, func(tx store.TodoStore) error {
+			if err := tx.Put(ctx, newTodo("discarded")); err != nil {
+				return err
+			}
+			if err := tx.Delete(ctx, "kept"); err != nil {
+				return err
+			}
+			if err := tx.Enqueue(ctx, &store.Operation{Kind: store.OperationCreate, TodoID: "discarded", Payload: []byte("{}")}); err != nil {
+				return err
+			}
+			// the transaction sees its own writes
+			if _, err := tx.Get(ctx, "discarded"); err != nil {
+				t.Errorf("Get inside the transaction: %v", err)
+			}
+			if _, err := tx.Get(ctx, "kept"); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("Get of a todo deleted inside the transaction: got %v, want ErrNotFound", err)
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("Tx returned %v, want the error from fn", err)
+		}
+		if _, err := s.Get(ctx, "discarded"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("todo put in a rolled back transaction: got %v, want ErrNotFound", err)
+		}
+		if _, err := s.Get(ctx, "kept"); err != nil {
+			t.Fatalf("todo deleted in a rolled back transaction: %v", err)
+		}
+		if ops, _ := s.PendingOperations(ctx); len(ops) != 0 {
+			t.Fatalf("rolled back transaction left %d operations queued", len(ops))
+		}
+
+		var opID int64
+		err = s.Tx(ctx, func(tx store.TodoStore) error {
+			if err := tx.Put(ctx, newTodo("committed")); err != nil {
+				return err
+			}
+			op := &store.Operation{Kind: store.OperationCreate, TodoID: "committed", Payload: []byte("{}")}
+			if err := tx.Enqueue(ctx, op); err != nil {
+				return err
+			}
+			opID = op.ID
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Tx: %v", err)
+		}
+		if _, err := s.Get(ctx, "committed"); err != nil {
+			t.Fatalf("todo put in a committed transaction: %v", err)
+		}
+		ops, err := s.PendingOperations(ctx)
+		if err != nil {
+			t.Fatalf("PendingOperations: %v", err)
+		}
+		if len(ops) != 1 || ops[0].ID != opID || ops[0].TodoID != "committed" {
+			t.Fatalf("PendingOperations returned %+v, want the operation queued with the todo", ops)
+		}
+	})
+}
+
+func TestOutbox(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+
+		var ids []int64
+		for _, todoID := range []string{"a", "b", "c"} {
+			op := &store.Operation{Kind: store.OperationUpdate, TodoID: todoID, Payload: []byte(`{"todo":"` + todoID + `"}`)}
+			if err := s.Enqueue(ctx, op); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+			if len(ids) > 0 && op.ID <= ids[len(ids)-1] {
+				t.Fatalf("Enqueue gave id %d after %d, ids must increase", op.ID, ids[len(ids)-1])
+			}
+			ids = append(ids, op.ID)
+		}
+
+		ops, err := s.PendingOperations(ctx)
+		if err != nil {
+			t.Fatalf("PendingOperations: %v", err)
+		}
+		if len(ops) != 3 || ops[0].TodoID != "a" || ops[2].TodoID != "c" {
+			t.Fatalf("PendingOperations returned %+v, want a, b and c in order", ops)
+		}
+		if string(ops[1].Payload) != `{"todo":"b"}` || ops[1].Kind != store.OperationUpdate {
+			t.Fatalf("operation came back as %+v", ops[1])
+		}
+
+		retried := ops[0]
+		retried.Attempts = 2
+		retried.LastError = "unavailable"
+		retried.NextAttempt = time.Unix(1800000000, 0)
+		if err := s.UpdateOperation(ctx, retried); err != nil {
+			t.Fatalf("UpdateOperation: %v", err)
+		}
+		failed := ops[1]
+		failed.Failed = true
+		if err := s.UpdateOperation(ctx, failed); err != nil {
+			t.Fatalf("UpdateOperation: %v", err)
+		}
+		if err := s.DeleteOperation(ctx, ids[2]); err != nil {
+			t.Fatalf("DeleteOperation: %v", err)
+		}
+
+		ops, err = s.PendingOperations(ctx)
+		if err != nil {
+			t.Fatalf("PendingOperations: %v", err)
+		}
+		if len(ops) != 1 {
+			t.Fatalf("PendingOperations returned %d operations, want only the retried one", len(ops))
+		}
+		got := ops[0]
+		if got.ID != ids[0] || got.Attempts != 2 || got.LastError != "unavailable" || !got.NextAttempt.Equal(retried.NextAttempt) {
+			t.Fatalf("retried operation came back as %+v", got)
+		}
+	})
+}
+
+func TestIdempotency(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		now := time.Unix(1700000000, 0)
+
+		if _, err := s.GetIdempotency(ctx, "/todo.TodoService/CreateTodo", "k"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("GetIdempotency of a missing key: got %v, want ErrNotFound", err)
+		}
+
+		rec := &store.IdempotencyRecord{
+			Method:       "/todo.TodoService/CreateTodo",
+			Key:          "k",
+			RequestHash:  []byte{1, 2, 3},
+			ResponseType: "todo.Todo",
+			Response:     []byte{4, 5},
+			CreatedAt:    now,
+			ExpiresAt:    now.Add(time.Hour),
+		}
+		expired := *rec
+		expired.Key = "old"
+		expired.ExpiresAt = now.Add(-time.Minute)
+		for _, r := range []*store.IdempotencyRecord{rec, &expired} {
+			if err := s.PutIdempotency(ctx, r); err != nil {
+				t.Fatalf("PutIdempotency: %v", err)
+			}
+		}
+
+		got, err := s.GetIdempotency(ctx, rec.Method, rec.Key)
+		if err != nil {
+			t.Fatalf("GetIdempotency: %v", err)
+		}
+		if string(got.RequestHash) != string(rec.RequestHash) || string(got.Response) != string(rec.Response) ||
+			got.ResponseType != rec.ResponseType || !got.ExpiresAt.Equal(rec.ExpiresAt) {
+			t.Fatalf("GetIdempotency returned %+v, want %+v", got, rec)
+		}
+		// keys are scoped to their method
+		if _, err := s.GetIdempotency(ctx, "/todo.TodoService/UpdateTodo", rec.Key); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("GetIdempotency for another method: got %v, want ErrNotFound", err)
+		}
+
+		removed, err := s.DeleteExpiredIdempotency(ctx, now)
+		if err != nil {
+			t.Fatalf("DeleteExpiredIdempotency: %v", err)
+		}
+		if removed != 1 {
+			t.Fatalf("DeleteExpiredIdempotency removed %d records, want 1", removed)
+		}
+		if _, err := s.GetIdempotency(ctx, expired.Method, expired.Key); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("GetIdempotency of an expired key: got %v, want ErrNotFound", err)
+		}
+		if _, err := s.GetIdempotency(ctx, rec.Method, rec.Key); err != nil {
+			t.Fatalf("GetIdempotency of a live key after purging: %v", err)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+// Package tenant keeps each team's todos apart.
+//
+// Every RPC runs for one tenant. When callers authenticate, it is the tenant of
+// their API key or JWT. Otherwise it is whatever the x-tenant-id header says.
+// Callers without a tenant share the default tenant, "". The interceptors put the
+// tenant in the context and handlers only ever see the todos that belong to it.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/jerryhong21/todo-grpc/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Header is the metadata a caller names their tenant in
+const Header = "x-tenant-id"
+
+// Default is the tenant of callers that don't have one
+const Default = ""
+
+var validTenant = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+type tenantKey struct{}
+
+// NewContext returns a copy of ctx for tenant
+func NewContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// FromContext returns the tenant the RPC runs for, Default if none was set
+func FromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// Valid reports whether name can be used as a tenant id
+func Valid(name string) bool {
+	return validTenant.MatchString(name)
+}
+
+// UnaryInterceptor runs the RPC for the caller's tenant
+func UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	tenant, err := Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(NewContext(ctx, tenant), req)
+}
+
+// StreamInterceptor runs the RPC for the caller's tenant
+func StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	tenant, err := Resolve(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &tenantStream{ServerStream: ss, ctx: NewContext(ss.Context(), tenant)})
+}
+
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
+
+// Resolve works out the caller's tenant. An authenticated caller's tenant comes from
+// their credential and the header can't change it, it may only repeat it.
+func Resolve(ctx context.Context) (string, error) {
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(Header); len(values) > 0 {
+			header = values[0]
+		}
+	}
+	if header != "" && !Valid(header) {
+		return "", status.Errorf(codes.InvalidArgument, "%s must be 1 to 64 letters, digits, '.', '_' or '-'", Header)
+	}
+
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return header, nil
+	}
+	if header != "" && header != id.Tenant {
+		return "", status.Error(codes.PermissionDenied, fmt.Sprintf("%s doesn't match the tenant of your credentials", Header))
+	}
+	if id.Tenant != "" && !Valid(id.Tenant) {
+		return "", status.Error(codes.PermissionDenied, "your credentials name an invalid tenant")
+	}
+	return id.Tenant, nil
+}
+
+// Quotas caps how many todos each tenant can have, 0 means no cap
+type Quotas struct {
+	MaxTodos  int            // for tenants without their own cap
+	PerTenant map[string]int // overrides MaxTodos by tenant
+}
+
+// Limit is the most todos tenant can have, 0 if there is no cap
+func (q Quotas) Limit(tenant string) int {
+	if limit, ok := q.PerTenant[tenant]; ok {
+		return limit
+	}
+	return q.MaxTodos
+}
@@ -0,0 +1,63 @@
+package tenant_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jerryhong21/todo-grpc/auth"
+	"github.com/jerryhong21/todo-grpc/tenant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		credential *auth.Identity // nil when the caller isn't authenticated
+		want       string
+		wantCode   codes.Code
+	}{
+		{name: "no header", want: tenant.Default},
+		{name: "header", header: "team-a", want: "team-a"},
+		{name: "header with every allowed character", header: "Team_a.2-b", want: "Team_a.2-b"},
+		{name: "header starting with a dash", header: "-team", wantCode: codes.InvalidArgument},
+		{name: "header with a slash", header: "team/a", wantCode: codes.InvalidArgument},
+		{name: "header too long", header: strings.Repeat("a", 65), wantCode: codes.InvalidArgument},
+		{name: "credential", credential: &auth.Identity{Subject: "alice", Tenant: "team-a"}, want: "team-a"},
+		{name: "credential without a tenant", credential: &auth.Identity{Subject: "alice"}, want: tenant.Default},
+		{name: "header repeating the credential's tenant", header: "team-a", credential: &auth.Identity{Subject: "alice", Tenant: "team-a"}, want: "team-a"},
+		{name: "header contradicting the credential", header: "team-b", credential: &auth.Identity{Subject: "alice", Tenant: "team-a"}, wantCode: codes.PermissionDenied},
+		{name: "header naming a tenant for a credential without one", header: "team-b", credential: &auth.Identity{Subject: "alice"}, wantCode: codes.PermissionDenied},
+		{name: "credential naming an invalid tenant", credential: &auth.Identity{Subject: "alice", Tenant: "team a"}, wantCode: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(tenant.Header, tt.header))
+			}
+			if tt.credential != nil {
+				ctx = auth.NewContext(ctx, tt.credential)
+			}
+			got, err := tenant.Resolve(ctx)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Resolve returned %v, want %v", err, tt.wantCode)
+			}
+			if err == nil && got != tt.want {
+				t.Fatalf("Resolve returned %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotasLimit(t *testing.T) {
+	q := tenant.Quotas{MaxTodos: 10, PerTenant: map[string]int{"big": 100, "unlimited": 0}}
+	for tenantID, want := range map[string]int{"team-a": 10, tenant.Default: 10, "big": 100, "unlimited": 0} {
+		if got := q.Limit(tenantID); got != want {
+			t.Fatalf("Limit(%q) = %d, want %d", tenantID, got, want)
+		}
+	}
+}
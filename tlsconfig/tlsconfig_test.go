@@ -0,0 +1,167 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/tlsconfig"
+)
+
+// serve accepts TLS connections with cfg, answering every completed handshake with one byte
+func serve(t *testing.T, cfg *tls.Config) string {
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := conn.(*tls.Conn).Handshake(); err == nil {
+					conn.Write([]byte{1})
+				}
+			}()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+// dial connects and waits for the server's byte, so a client certificate the server turns down
+// fails here even under TLS 1.3, where the client finishes its side of the handshake first
+func dial(addr string, cfg *tls.Config) (*tls.ConnectionState, error) {
+	// as gRPC's credentials.NewTLS does
+	cfg = cfg.Clone()
+	cfg.NextProtos = []string{"h2"}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		return nil, err
+	}
+	state := conn.ConnectionState()
+	return &state, nil
+}
+
+func TestHandshake(t *testing.T) {
+	certs, err := tlsconfig.WriteDevCerts(t.TempDir(), []string{"localhost", "127.0.0.1"}, "test-client")
+	if err != nil {
+		t.Fatalf("WriteDevCerts: %v", err)
+	}
+	withClientCert, err := tlsconfig.Client(certs.CAFile, certs.ClientCertFile, certs.ClientKeyFile, "localhost")
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	withoutClientCert, err := tlsconfig.Client(certs.CAFile, "", "", "localhost")
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	otherCA, err := tlsconfig.WriteDevCerts(t.TempDir(), []string{"localhost"}, "test-client")
+	if err != nil {
+		t.Fatalf("WriteDevCerts: %v", err)
+	}
+	untrusting, err := tlsconfig.Client(otherCA.CAFile, "", "", "localhost")
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		clientCA string // turns on mutual TLS
+		client   *tls.Config
+		wantErr  bool
+	}{
+		{name: "TLS", client: withoutClientCert},
+		{name: "TLS with a client that doesn't trust the CA", client: untrusting, wantErr: true},
+		{name: "mTLS", clientCA: certs.CAFile, client: withClientCert},
+		{name: "mTLS without a client certificate", clientCA: certs.CAFile, client: withoutClientCert, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tlsconfig.NewReloader(certs.ServerCertFile, certs.ServerKeyFile, tt.clientCA)
+			if err != nil {
+				t.Fatalf("NewReloader: %v", err)
+			}
+			state, err := dial(serve(t, r.Config()), tt.client)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("handshake succeeded, want it refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("handshake: %v", err)
+			}
+			if state.NegotiatedProtocol != "h2" {
+				t.Fatalf("negotiated %q, gRPC needs h2", state.NegotiatedProtocol)
+			}
+		})
+	}
+}
+
+func TestReloaderRotates(t *testing.T) {
+	dir := t.TempDir()
+	old, err := tlsconfig.WriteDevCerts(dir, []string{"localhost"}, "test-client")
+	if err != nil {
+		t.Fatalf("WriteDevCerts: %v", err)
+	}
+	r, err := tlsconfig.NewReloader(old.ServerCertFile, old.ServerKeyFile, "")
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	addr := serve(t, r.Config())
+	before, err := tlsconfig.Client(old.CAFile, "", "", "localhost")
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	if _, err := dial(addr, before); err != nil {
+		t.Fatalf("handshake before rotating: %v", err)
+	}
+	// let the watcher start before the files change
+	time.Sleep(100 * time.Millisecond)
+
+	rotated, err := tlsconfig.WriteDevCerts(t.TempDir(), []string{"localhost"}, "test-client")
+	if err != nil {
+		t.Fatalf("WriteDevCerts: %v", err)
+	}
+	for src, dst := range map[string]string{rotated.ServerKeyFile: old.ServerKeyFile, rotated.ServerCertFile: old.ServerCertFile} {
+		content, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// only the new CA's clients can connect once the rotated certificate is served
+	client, err := tlsconfig.Client(rotated.CAFile, "", "", "localhost")
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := dial(addr, client)
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the rotated certificate was never served: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
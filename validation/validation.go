@@ -0,0 +1,267 @@
+// Package validation enforces the (todo.validate.field) rules declared in the proto files.
+//
+// The interceptors check every request before its handler runs and reject it with
+// INVALID_ARGUMENT and a BadRequest detail listing each field that broke a rule, so
+// handlers can assume ids are UUIDs, titles fit, and so on.
+package validation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnaryInterceptor validates the request of a unary RPC
+func UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if msg, ok := req.(proto.Message); ok {
+		if err := Check(msg); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor validates every message a client sends on a stream
+func StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validatingStream{ServerStream: ss})
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if msg, ok := m.(proto.Message); ok {
+		return Check(msg)
+	}
+	return nil
+}
+
+// Check returns the INVALID_ARGUMENT error for msg's rule violations, or nil if it follows every rule
+func Check(msg proto.Message) error {
+	violations := Validate(msg)
+	if len(violations) == 0 {
+		return nil
+	}
+	return InvalidArgument(violations...)
+}
+
+// Validate lists the fields of msg, and of the messages inside it, that break their rules
+func Validate(msg proto.Message) []*errdetails.BadRequest_FieldViolation {
+	return validateMessage("", msg.ProtoReflect())
+}
+
+// InvalidArgument is the INVALID_ARGUMENT error for a request with bad fields, listed in a BadRequest detail
+func InvalidArgument(violations ...*errdetails.BadRequest_FieldViolation) error {
+	fields := make([]string, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, v.GetField())
+	}
+	st := status.Newf(codes.InvalidArgument, "invalid %s", strings.Join(fields, ", "))
+	if withDetails, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// Violation is a shorthand for building a field violation
+func Violation(field, format string, args ...any) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Description: fmt.Sprintf(format, args...)}
+}
+
+func validateMessage(prefix string, m protoreflect.Message) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+		rules := fieldRules(fd)
+
+		switch {
+		case fd.IsList():
+			list := m.Get(fd).List()
+			violations = append(violations, checkList(path, fd, list, rules)...)
+		case fd.IsMap():
+			violations = append(violations, checkMap(path, fd, m.Get(fd).Map(), rules)...)
+		case fd.Message() != nil:
+			if !m.Has(fd) {
+				if rules.GetRequired() {
+					violations = append(violations, Violation(path, "%s is required", fd.Name()))
+				}
+				continue
+			}
+			violations = append(violations, validateMessage(path+".", m.Get(fd).Message())...)
+		default:
+			if v := checkScalar(path, string(fd.Name()), m.Get(fd), rules); v != nil {
+				violations = append(violations, v)
+			}
+		}
+	}
+	return violations
+}
+
+func checkList(path string, fd protoreflect.FieldDescriptor, list protoreflect.List, rules *pb.FieldRules) []*errdetails.BadRequest_FieldViolation {
+	n := uint64(list.Len())
+	if rules.GetRequired() && n == 0 {
+		return []*errdetails.BadRequest_FieldViolation{Violation(path, "%s must not be empty", fd.Name())}
+	}
+	if r := rules.GetRepeated(); r != nil {
+		if r.MinItems != nil && n < r.GetMinItems() {
+			return []*errdetails.BadRequest_FieldViolation{Violation(path, "%s needs at least %d items", fd.Name(), r.GetMinItems())}
+		}
+		if r.MaxItems != nil && n > r.GetMaxItems() {
+			return []*errdetails.BadRequest_FieldViolation{Violation(path, "%s can have at most %d items", fd.Name(), r.GetMaxItems())}
+		}
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	for i := 0; i < list.Len(); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if fd.Message() != nil {
+			violations = append(violations, validateMessage(itemPath+".", list.Get(i).Message())...)
+			continue
+		}
+		if v := checkScalar(itemPath, "item", list.Get(i), rules.GetRepeated().GetItems()); v != nil {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+func checkMap(path string, fd protoreflect.FieldDescriptor, m protoreflect.Map, rules *pb.FieldRules) []*errdetails.BadRequest_FieldViolation {
+	n := uint64(m.Len())
+	if rules.GetRequired() && n == 0 {
+		return []*errdetails.BadRequest_FieldViolation{Violation(path, "%s must not be empty", fd.Name())}
+	}
+	r := rules.GetMap()
+	if r != nil && r.MaxPairs != nil && n > r.GetMaxPairs() {
+		return []*errdetails.BadRequest_FieldViolation{Violation(path, "%s can have at most %d entries", fd.Name(), r.GetMaxPairs())}
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	m.Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+		entryPath := fmt.Sprintf("%s[%q]", path, key.String())
+		if v := checkScalar(entryPath, "key", key.Value(), r.GetKeys()); v != nil {
+			violations = append(violations, v)
+		}
+		if fd.MapValue().Message() != nil {
+			violations = append(violations, validateMessage(entryPath+".", value.Message())...)
+		} else if v := checkScalar(entryPath, "value", value, r.GetValues()); v != nil {
+			violations = append(violations, v)
+		}
+		return true
+	})
+	return violations
+}
+
+// checkScalar applies rules to a single string or number, name is how the description refers to it
+func checkScalar(path, name string, value protoreflect.Value, rules *pb.FieldRules) *errdetails.BadRequest_FieldViolation {
+	if rules == nil {
+		return nil
+	}
+	if isZero(value) {
+		if rules.GetRequired() {
+			return Violation(path, "%s is required", name)
+		}
+		if rules.GetIgnoreEmpty() {
+			return nil
+		}
+	}
+
+	switch v := value.Interface().(type) {
+	case string:
+		r := rules.GetString_()
+		if r == nil {
+			return nil
+		}
+		length := uint64(utf8.RuneCountInString(v))
+		if r.MinLen != nil && length < r.GetMinLen() {
+			if r.GetMinLen() == 1 {
+				return Violation(path, "%s must not be empty", name)
+			}
+			return Violation(path, "%s must be at least %d characters", name, r.GetMinLen())
+		}
+		if r.MaxLen != nil && length > r.GetMaxLen() {
+			return Violation(path, "%s must be at most %d characters", name, r.GetMaxLen())
+		}
+		if r.GetUuid() && !isUUID(v) {
+			return Violation(path, "%q is not a UUID like 0192a3c4-5b6d-7e8f-9a0b-1c2d3e4f5a6b", v)
+		}
+	case int32:
+		r := rules.GetInt32()
+		if r == nil {
+			return nil
+		}
+		if r.Gte != nil && v < r.GetGte() {
+			return Violation(path, "%s must be at least %d", name, r.GetGte())
+		}
+		if r.Lte != nil && v > r.GetLte() {
+			return Violation(path, "%s must be at most %d", name, r.GetLte())
+		}
+	case int64:
+		r := rules.GetInt64()
+		if r == nil {
+			return nil
+		}
+		if r.Gte != nil && v < r.GetGte() {
+			return Violation(path, "%s must be at least %d", name, r.GetGte())
+		}
+		if r.Lte != nil && v > r.GetLte() {
+			return Violation(path, "%s must be at most %d", name, r.GetLte())
+		}
+	}
+	return nil
+}
+
+func fieldRules(fd protoreflect.FieldDescriptor) *pb.FieldRules {
+	opts := fd.Options()
+	if opts == nil || !proto.HasExtension(opts, pb.E_Field) {
+		return nil
+	}
+	return proto.GetExtension(opts, pb.E_Field).(*pb.FieldRules)
+}
+
+func isZero(value protoreflect.Value) bool {
+	switch v := value.Interface().(type) {
+	case string:
+		return v == ""
+	case []byte:
+		return len(v) == 0
+	case bool:
+		return !v
+	case int32:
+		return v == 0
+	case int64:
+		return v == 0
+	case uint32:
+		return v == 0
+	case uint64:
+		return v == 0
+	case float32:
+		return v == 0
+	case float64:
+		return v == 0
+	case protoreflect.EnumNumber:
+		return v == 0
+	}
+	return false
+}
+
+// isUUID only accepts the hyphenated form, which is how ids are stored
+func isUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil && len(s) == 36
+}
@@ -0,0 +1,166 @@
+package validation_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/jerryhong21/todo-grpc/proto"
+	"github.com/jerryhong21/todo-grpc/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const id = "0192a3c4-5b6d-7e8f-9a0b-1c2d3e4f5a6b"
+
+func manyIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = id
+	}
+	return ids
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want []string // "<field>: <description>" for every violation, in order
+	}{
+		{
+			name: "valid create",
+			msg:  &pb.CreateTodoRequest{Id: id, Title: "title"},
+		},
+		{
+			name: "ignore_empty skips the rules of an unset id",
+			msg:  &pb.CreateTodoRequest{Title: "title"},
+		},
+		{
+			name: "ignore_empty still checks a set id",
+			msg:  &pb.CreateTodoRequest{Id: "not-a-uuid", Title: "title"},
+			want: []string{`id: "not-a-uuid" is not a UUID like 0192a3c4-5b6d-7e8f-9a0b-1c2d3e4f5a6b`},
+		},
+		{
+			name: "required id",
+			msg:  &pb.GetTodoRequest{},
+			want: []string{"id: id is required"},
+		},
+		{
+			name: "UUID without hyphens",
+			msg:  &pb.GetTodoRequest{Id: strings.ReplaceAll(id, "-", "")},
+			want: []string{fmt.Sprintf("id: %q is not a UUID like 0192a3c4-5b6d-7e8f-9a0b-1c2d3e4f5a6b", strings.ReplaceAll(id, "-", ""))},
+		},
+		{
+			name: "UUID in upper case",
+			msg:  &pb.GetTodoRequest{Id: strings.ToUpper(id)},
+		},
+		{
+			name: "string lengths count characters",
+			msg:  &pb.CreateTodoRequest{Title: strings.Repeat("é", 200)},
+		},
+		{
+			name: "every field is reported",
+			msg:  &pb.CreateTodoRequest{Title: strings.Repeat("x", 201), Description: strings.Repeat("x", 5001)},
+			want: []string{
+				"title: title must be at most 200 characters",
+				"description: description must be at most 5000 characters",
+			},
+		},
+		{
+			name: "min_len 1 reads as not empty",
+			msg:  &pb.CreateTodoRequest{},
+			want: []string{"title: title must not be empty"},
+		},
+		{
+			name: "int32 gte",
+			msg:  &pb.ListTodosRequest{PageSize: -1},
+			want: []string{"page_size: page_size must be at least 0"},
+		},
+		{
+			name: "int64 gte",
+			msg:  &pb.UpdateTodoRequest{Id: id, Version: -1},
+			want: []string{"version: version must be at least 0"},
+		},
+		{
+			name: "list items are reported by index",
+			msg:  &pb.BulkDeleteTodoRequest{Ids: []string{id, id, "nope", ""}},
+			want: []string{
+				`ids[2]: "nope" is not a UUID like 0192a3c4-5b6d-7e8f-9a0b-1c2d3e4f5a6b`,
+				"ids[3]: item is required",
+			},
+		},
+		{
+			name: "max_items",
+			msg:  &pb.BulkDeleteTodoRequest{Ids: manyIDs(1001)},
+			want: []string{"ids: ids can have at most 1000 items"},
+		},
+		{
+			name: "max_items allows the limit",
+			msg:  &pb.BulkDeleteTodoRequest{Ids: manyIDs(1000)},
+		},
+		{
+			name: "item string rules",
+			msg:  &pb.AssignTodoRequest{Id: id, Assignees: []string{"alice", ""}},
+			want: []string{"assignees[1]: item must not be empty"},
+		},
+		{
+			name: "map keys and values",
+			msg:  &pb.BulkDeleteTodoRequest{Ids: []string{id}, Versions: map[string]int64{"nope": 1, id: 0}},
+			want: []string{
+				`versions["nope"]: "nope" is not a UUID like 0192a3c4-5b6d-7e8f-9a0b-1c2d3e4f5a6b`,
+				fmt.Sprintf(`versions[%q]: value must be at least 1`, id),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range validation.Validate(tt.msg) {
+				got = append(got, v.GetField()+": "+v.GetDescription())
+			}
+			// map entries come out in no particular order
+			if _, isBulk := tt.msg.(*pb.BulkDeleteTodoRequest); isBulk {
+				slices.Sort(got)
+				slices.Sort(tt.want)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("got violations\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return req, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/todo.TodoService/GetTodo"}
+
+	_, err := validation.UnaryInterceptor(context.Background(), &pb.GetTodoRequest{Id: "nope"}, info, handler)
+	if status.Code(err) != codes.InvalidArgument || called {
+		t.Fatalf("got %v with the handler called %v, want INVALID_ARGUMENT before the handler", err, called)
+	}
+	var badRequest *errdetails.BadRequest
+	for _, d := range status.Convert(err).Details() {
+		if b, ok := d.(*errdetails.BadRequest); ok {
+			badRequest = b
+		}
+	}
+	if len(badRequest.GetFieldViolations()) != 1 || badRequest.GetFieldViolations()[0].GetField() != "id" {
+		t.Fatalf("error carries %v, want a BadRequest naming id", badRequest)
+	}
+	if msg := status.Convert(err).Message(); msg != "invalid id" {
+		t.Fatalf("message is %q, want the bad fields listed", msg)
+	}
+
+	if _, err := validation.UnaryInterceptor(context.Background(), &pb.GetTodoRequest{Id: id}, info, handler); err != nil || !called {
+		t.Fatalf("valid request got %v with the handler called %v", err, called)
+	}
+}
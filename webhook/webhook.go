@@ -0,0 +1,172 @@
+// Package webhook receives SafetyCulture webhook events for actions, so edits made
+// in the SC app show up locally within seconds instead of waiting for the next reconciliation.
+//
+// Every request must be signed with the shared secret configured for the webhook:
+// the X-SafetyCulture-Signature header holds "sha256=<hex>", the HMAC-SHA256 of
+// "<X-SafetyCulture-Timestamp>.<raw body>". Requests with a bad signature, or a
+// timestamp outside MaxSkew, are rejected before the body is looked at.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/reconcile"
+)
+
+const (
+	SignatureHeader = "X-SafetyCulture-Signature"
+	TimestampHeader = "X-SafetyCulture-Timestamp"
+)
+
+// DefaultMaxSkew is how old (or how far in the future) a signed request may be, to stop replays
+const DefaultMaxSkew = 5 * time.Minute
+
+// maxBodySize caps the payload we are willing to read, events are small
+const maxBodySize = 1 << 20
+
+// applyTimeout bounds the SC lookup and store write for one event
+const applyTimeout = 10 * time.Second
+
+// Action trigger events, anything else is acknowledged and ignored
+const (
+	EventActionCreated = "TRIGGER_EVENT_ACTION_CREATED"
+	EventActionUpdated = "TRIGGER_EVENT_ACTION_UPDATED"
+	EventActionDeleted = "TRIGGER_EVENT_ACTION_DELETED"
+)
+
+var errBadSignature = errors.New("invalid webhook signature")
+
+// Event is the part of an SC webhook payload we need
+type Event struct {
+	WebhookID string `json:"webhook_id"`
+	Event     struct {
+		DateTriggered time.Time `json:"date_triggered"`
+		EventTypes    []string  `json:"event_types"`
+	} `json:"event"`
+	Resource struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	} `json:"resource"`
+}
+
+// Applier updates one todo from its current SC action, reconcile.Reconciler implements it
+type Applier interface {
+	Apply(ctx context.Context, id string) (*reconcile.Report, error)
+}
+
+// Handler verifies and applies SC webhook events
+type Handler struct {
+	Secret  []byte
+	MaxSkew time.Duration
+
+	applier Applier
+}
+
+func NewHandler(secret string, applier Applier) *Handler {
+	return &Handler{
+		Secret:  []byte(secret),
+		MaxSkew: DefaultMaxSkew,
+		applier: applier,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := h.verify(r.Header, body, time.Now()); err != nil {
+		log.Printf("Rejected SafetyCulture webhook: %v", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "malformed event", http.StatusBadRequest)
+		return
+	}
+	if !isActionEvent(event) || event.Resource.ID == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// SC waits on the response, so don't let one slow lookup hold the connection forever
+	ctx, cancel := context.WithTimeout(r.Context(), applyTimeout)
+	defer cancel()
+
+	report, err := h.applier.Apply(ctx, event.Resource.ID)
+	if err != nil {
+		// a 5xx makes SC deliver the event again later
+		log.Printf("Failed to apply SafetyCulture webhook for todo %s: %v", event.Resource.ID, err)
+		http.Error(w, "failed to apply event", http.StatusServiceUnavailable)
+		return
+	}
+	for _, c := range report.Conflicts {
+		log.Printf("Webhook conflict on todo %s: %s", c.ID, c.Reason)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verify checks the request signature and that its timestamp is recent
+func (h *Handler) verify(header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errBadSignature
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)).Abs(); skew > h.MaxSkew {
+		return errors.New("webhook timestamp is too old or in the future")
+	}
+
+	signature, found := strings.CutPrefix(header.Get(SignatureHeader), "sha256=")
+	if !found {
+		return errBadSignature
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return errBadSignature
+	}
+	if !hmac.Equal(got, Sign(h.Secret, timestamp, body)) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// Sign returns the HMAC-SHA256 a sender puts in the signature header, hex encoded after "sha256="
+func Sign(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func isActionEvent(event Event) bool {
+	for _, t := range event.Event.EventTypes {
+		switch t {
+		case EventActionCreated, EventActionUpdated, EventActionDeleted:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jerryhong21/todo-grpc/external"
+	"github.com/jerryhong21/todo-grpc/external/scfake"
+	"github.com/jerryhong21/todo-grpc/outbox"
+	"github.com/jerryhong21/todo-grpc/reconcile"
+	"github.com/jerryhong21/todo-grpc/store"
+	"github.com/jerryhong21/todo-grpc/webhook"
+)
+
+const secret = "webhook-secret"
+
+// applier records the ids it is asked to apply
+type applier struct {
+	mu  sync.Mutex
+	ids []string
+	err error
+}
+
+func (a *applier) Apply(ctx context.Context, id string) (*reconcile.Report, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ids = append(a.ids, id)
+	if a.err != nil {
+		return nil, a.err
+	}
+	return &reconcile.Report{}, nil
+}
+
+func event(eventType, id string) []byte {
+	return []byte(`{"webhook_id":"w","event":{"date_triggered":"2024-01-01T00:00:00Z","event_types":["` + eventType + `"]},"resource":{"id":"` + id + `","type":"ACTION"}}`)
+}
+
+// signed builds a webhook request signed with key at the given time
+func signed(key string, at time.Time, body []byte) *http.Request {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/safetyculture", bytes.NewReader(body))
+	req.Header.Set(webhook.TimestampHeader, timestamp)
+	req.Header.Set(webhook.SignatureHeader, "sha256="+hex.EncodeToString(webhook.Sign([]byte(key), timestamp, body)))
+	return req
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Now()
+	updated := event(webhook.EventActionUpdated, "a")
+
+	tests := []struct {
+		name       string
+		req        func() *http.Request
+		applyErr   error
+		wantStatus int
+		wantApply  bool
+	}{
+		{
+			name:       "valid signature",
+			req:        func() *http.Request { return signed(secret, now, updated) },
+			wantStatus: http.StatusNoContent,
+			wantApply:  true,
+		},
+		{
+			name:       "signed with another secret",
+			req:        func() *http.Request { return signed("another-secret", now, updated) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "body changed after signing",
+			req: func() *http.Request {
+				req := signed(secret, now, updated)
+				req.Body = io.NopCloser(bytes.NewReader(event(webhook.EventActionUpdated, "b")))
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "no signature",
+			req: func() *http.Request {
+				req := signed(secret, now, updated)
+				req.Header.Del(webhook.SignatureHeader)
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "signature without the sha256 prefix",
+			req: func() *http.Request {
+				req := signed(secret, now, updated)
+				req.Header.Set(webhook.SignatureHeader, req.Header.Get(webhook.SignatureHeader)[len("sha256="):])
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "signature that isn't hex",
+			req: func() *http.Request {
+				req := signed(secret, now, updated)
+				req.Header.Set(webhook.SignatureHeader, "sha256=not-hex")
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "no timestamp",
+			req: func() *http.Request {
+				req := signed(secret, now, updated)
+				req.Header.Del(webhook.TimestampHeader)
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "stale timestamp",
+			req:        func() *http.Request { return signed(secret, now.Add(-webhook.DefaultMaxSkew-time.Minute), updated) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "timestamp in the future",
+			req:        func() *http.Request { return signed(secret, now.Add(webhook.DefaultMaxSkew+time.Minute), updated) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "timestamp within the skew",
+			req:        func() *http.Request { return signed(secret, now.Add(-time.Minute), updated) },
+			wantStatus: http.StatusNoContent,
+			wantApply:  true,
+		},
+		{
+			name:       "oversized body",
+			req:        func() *http.Request { return signed(secret, now, bytes.Repeat([]byte("x"), 1<<20+1)) },
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "malformed event",
+			req:        func() *http.Request { return signed(secret, now, []byte("{")) },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "not an action event",
+			req:        func() *http.Request { return signed(secret, now, event("TRIGGER_EVENT_INSPECTION_COMPLETED", "a")) },
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "apply fails",
+			req:        func() *http.Request { return signed(secret, now, updated) },
+			applyErr:   errors.New("SC is unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantApply:  true,
+		},
+		{
+			name:       "not a POST",
+			req:        func() *http.Request { return httptest.NewRequest(http.MethodGet, "/webhooks/safetyculture", nil) },
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &applier{err: tt.applyErr}
+			rec := httptest.NewRecorder()
+			webhook.NewHandler(secret, a).ServeHTTP(rec, tt.req())
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if applied := len(a.ids) > 0; applied != tt.wantApply {
+				t.Fatalf("applied %v, want applied %v", a.ids, tt.wantApply)
+			}
+			if tt.wantApply && a.ids[0] != "a" {
+				t.Fatalf("applied %v, want a", a.ids)
+			}
+		})
+	}
+}
+
+func TestScfakeEvents(t *testing.T) {
+	fake := scfake.New("token")
+	t.Cleanup(fake.Close)
+	todos := store.NewMemoryStore()
+	sc := external.NewSCClient(fake.URL, "token")
+
+	// the events scfake sends are signed the way the handler checks them
+	receiver := httptest.NewServer(webhook.NewHandler(secret, reconcile.NewReconciler(todos, sc, outbox.NewWorker(todos, sc))))
+	t.Cleanup(receiver.Close)
+	fake.SetWebhook(receiver.URL, secret)
+
+	_, err := sc.CreateAction(context.Background(), &external.CreateActionRequest{TaskID: "a", Title: "made in the web app"})
+	if err != nil {
+		t.Fatalf("CreateAction: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		todo, err := todos.Get(context.Background(), "a")
+		if err == nil {
+			if todo.GetTitle() != "made in the web app" {
+				t.Fatalf("webhook stored %v", todo)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the action never arrived through the webhook: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}